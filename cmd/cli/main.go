@@ -8,6 +8,7 @@ import (
 	"github.com/spf13/pflag"
 
 	"github.com/keboola/processor-split-table/internal/pkg/cli"
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
 	"github.com/keboola/processor-split-table/internal/pkg/log"
 )
 
@@ -32,6 +33,12 @@ func exitWithError(logger log.Logger, err any) {
 		if errors.Is(e, pflag.ErrHelp) {
 			os.Exit(1)
 		}
+
+		// Exit with the custom exit code, the reason has already been logged
+		var exitCodeErr *kbc.ExitCodeError
+		if errors.As(e, &exitCodeErr) {
+			os.Exit(exitCodeErr.ExitCode())
+		}
 	}
 
 	// Get message
@@ -5,11 +5,11 @@ import (
 	"fmt"
 	"os"
 	"runtime/debug"
-	"runtime/pprof"
 
 	"github.com/keboola/processor-split-table/internal/pkg/kbc"
 	"github.com/keboola/processor-split-table/internal/pkg/log"
 	"github.com/keboola/processor-split-table/internal/pkg/processor"
+	"github.com/keboola/processor-split-table/internal/pkg/profiling"
 )
 
 func main() {
@@ -22,14 +22,8 @@ func main() {
 		}
 	}()
 
-	// Cpu profiling can be enabled by flag
-	if started, err := startCPUProfileIfFlagSet(); err != nil {
-		exitWithError(logger, err)
-	} else if started {
-		defer pprof.StopCPUProfile()
-	}
-
-	if err := processor.Run(logger); err != nil {
+	// Profiling can be enabled by flags, or by the configuration
+	if err := processor.Run(logger, parseProfilingFlags()); err != nil {
 		exitWithError(logger, err)
 	}
 }
@@ -60,20 +54,15 @@ func exitWithError(logger log.Logger, err any) {
 	os.Exit(exitCode)
 }
 
-func startCPUProfileIfFlagSet() (bool, error) {
-	ptr := flag.String("cpuprofile", "", "write cpu profile to the specified file")
+func parseProfilingFlags() profiling.Config {
+	cfg := profiling.Config{}
+	flag.StringVar(&cfg.CPUProfile, "cpuprofile", "", "write cpu profile to the specified file")
+	flag.StringVar(&cfg.HeapProfile, "memprofile", "", "write heap profile to the specified file")
+	flag.StringVar(&cfg.AllocsProfile, "allocsprofile", "", "write allocs profile to the specified file")
+	flag.StringVar(&cfg.BlockProfile, "blockprofile", "", "write goroutine blocking profile to the specified file")
+	flag.StringVar(&cfg.MutexProfile, "mutexprofile", "", "write mutex contention profile to the specified file")
+	flag.StringVar(&cfg.Trace, "trace", "", "write execution trace to the specified file")
+	flag.StringVar(&cfg.PprofAddr, "pprof-addr", "", "serve pprof profiles on the address")
 	flag.Parse()
-	if *ptr != "" {
-		f, err := os.Create(*ptr)
-		if err != nil {
-			return false, err
-		}
-		err = pprof.StartCPUProfile(f)
-		if err != nil {
-			return false, err
-		}
-		return true, nil
-	}
-
-	return false, nil
+	return cfg
 }
@@ -0,0 +1,218 @@
+// Package cgroup detects the CPU quota and the memory limit of the container, from the cgroup v1 or v2 files.
+// The limits are used as the defaults of GOMAXPROCS, the gzip concurrency and the soft memory limit, see Apply.
+package cgroup
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/c2h5oh/datasize"
+
+	"github.com/keboola/processor-split-table/internal/pkg/log"
+	"github.com/keboola/processor-split-table/internal/pkg/utils"
+)
+
+const (
+	procCgroupFile = "proc/self/cgroup"
+	mountDir       = "sys/fs/cgroup"
+	// v2ControllersFile exists only in the cgroup v2 unified hierarchy.
+	v2ControllersFile = mountDir + "/cgroup.controllers"
+	// v1NoLimit is the lowest value meaning no memory limit in cgroup v1, it is math.MaxInt64 rounded to the page size.
+	v1NoLimit = 1 << 62
+	// memoryLimitRatio of the cgroup memory limit is used as the soft memory limit,
+	// the rest is left for the memory not managed by the Go runtime.
+	memoryLimitRatio = 0.9
+)
+
+// Limits of the cgroup, zero values mean no limit.
+type Limits struct {
+	// CPUs is the CPU quota divided by the period, for example 1.5 threads.
+	CPUs float64
+	// Memory is the hard memory limit of the cgroup.
+	Memory datasize.ByteSize
+}
+
+// Detect reads limits of the cgroup of the current process, undetectable limits are zero.
+func Detect() Limits {
+	return detect(os.DirFS("/"))
+}
+
+// MaxProcs returns GOMAXPROCS according to the CPU quota, false is returned, if there is no lower quota.
+func (l Limits) MaxProcs() (int, bool) {
+	if l.CPUs <= 0 {
+		return 0, false
+	}
+	procs := max(int(math.Floor(l.CPUs)), 1)
+	return procs, procs < runtime.NumCPU()
+}
+
+// SoftMemoryLimit returns the soft memory limit according to the memory limit, false is returned, if there is no limit.
+func (l Limits) SoftMemoryLimit() (datasize.ByteSize, bool) {
+	if l.Memory == 0 {
+		return 0, false
+	}
+	return datasize.ByteSize(float64(l.Memory) * memoryLimitRatio), true
+}
+
+// Apply sets GOMAXPROCS and the soft memory limit (GOMEMLIMIT).
+//
+// GOMAXPROCS is set according to the CPU quota, if the GOMAXPROCS env is not set.
+// The auto gzip concurrency is GOMAXPROCS, so it follows the quota.
+//
+// The memoryLimit is used, if it is not zero.
+// Otherwise, the GOMEMLIMIT env is kept, if it is set, or the soft limit is derived from the cgroup memory limit.
+// If there is no limit, the fallbackMemoryLimit is used, zero means no limit.
+func Apply(logger log.Logger, limits Limits, memoryLimit, fallbackMemoryLimit datasize.ByteSize) {
+	if procs, ok := limits.MaxProcs(); ok && os.Getenv("GOMAXPROCS") == "" {
+		runtime.GOMAXPROCS(procs)
+		logger.Debugf("GOMAXPROCS set to %d by the cgroup CPU quota %.2f.", procs, limits.CPUs)
+	}
+
+	switch {
+	case memoryLimit > 0:
+		// Explicit value
+	case os.Getenv("GOMEMLIMIT") != "":
+		// The env is applied by the Go runtime
+		return
+	default:
+		if softLimit, ok := limits.SoftMemoryLimit(); ok {
+			memoryLimit = softLimit
+			logger.Debugf(
+				"Memory limit set to %s by the cgroup memory limit %s.",
+				utils.RemoveSpaces(memoryLimit.HumanReadable()),
+				utils.RemoveSpaces(limits.Memory.HumanReadable()),
+			)
+		} else {
+			memoryLimit = fallbackMemoryLimit
+		}
+	}
+
+	if memoryLimit > 0 {
+		debug.SetMemoryLimit(int64(memoryLimit.Bytes()))
+	}
+}
+
+func detect(fsys fs.FS) Limits {
+	paths := cgroupPaths(fsys)
+	limits := Limits{}
+
+	if _, err := fs.Stat(fsys, v2ControllersFile); err == nil {
+		// cgroup v2, the unified hierarchy
+		dir := path.Join(mountDir, paths[""])
+		walkUp(dir, mountDir, func(dir string) {
+			if cpus, ok := readV2CPUMax(fsys, path.Join(dir, "cpu.max")); ok {
+				limits.CPUs = minLimit(limits.CPUs, cpus)
+			}
+			if memory, ok := readUint(fsys, path.Join(dir, "memory.max")); ok {
+				limits.Memory = minLimit(limits.Memory, datasize.ByteSize(memory))
+			}
+		})
+		return limits
+	}
+
+	// cgroup v1, a hierarchy per controller
+	cpuDir := path.Join(mountDir, "cpu")
+	walkUp(path.Join(cpuDir, paths["cpu"]), cpuDir, func(dir string) {
+		quota, quotaOk := readInt(fsys, path.Join(dir, "cpu.cfs_quota_us"))
+		period, periodOk := readInt(fsys, path.Join(dir, "cpu.cfs_period_us"))
+		if quotaOk && periodOk && quota > 0 && period > 0 {
+			limits.CPUs = minLimit(limits.CPUs, float64(quota)/float64(period))
+		}
+	})
+	memoryDir := path.Join(mountDir, "memory")
+	walkUp(path.Join(memoryDir, paths["memory"]), memoryDir, func(dir string) {
+		if memory, ok := readUint(fsys, path.Join(dir, "memory.limit_in_bytes")); ok && memory < v1NoLimit {
+			limits.Memory = minLimit(limits.Memory, datasize.ByteSize(memory))
+		}
+	})
+	return limits
+}
+
+// cgroupPaths maps controllers to the cgroup paths of the current process, the cgroup v2 path has the empty key.
+func cgroupPaths(fsys fs.FS) map[string]string {
+	out := make(map[string]string)
+	f, err := fsys.Open(procCgroupFile)
+	if err != nil {
+		return out
+	}
+	defer f.Close()
+
+	// Each line has the format "hierarchy-ID:controller-list:cgroup-path"
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			out[controller] = strings.TrimPrefix(parts[2], "/")
+		}
+	}
+	return out
+}
+
+// walkUp calls the fn for the dir and each parent dir up to the root dir.
+// The process may not see its own cgroup dir, and a limit can be set by a parent cgroup.
+func walkUp(dir, root string, fn func(dir string)) {
+	for {
+		fn(dir)
+		if dir == root || !strings.HasPrefix(dir, root+"/") {
+			return
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// readV2CPUMax reads the "$MAX $PERIOD" format, "max" means no quota.
+func readV2CPUMax(fsys fs.FS, file string) (float64, bool) {
+	content, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return 0, false
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) != 2 || fields[0] == "max" {
+		return 0, false
+	}
+	quota, err1 := strconv.ParseFloat(fields[0], 64)
+	period, err2 := strconv.ParseFloat(fields[1], 64)
+	if err := errors.Join(err1, err2); err != nil || quota <= 0 || period <= 0 {
+		return 0, false
+	}
+	return quota / period, true
+}
+
+// readUint reads a number, "max" means no limit.
+func readUint(fsys fs.FS, file string) (uint64, bool) {
+	content, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+	return v, err == nil && v > 0
+}
+
+// readInt reads a number, it can be -1 in cgroup v1.
+func readInt(fsys fs.FS, file string) (int64, bool) {
+	content, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	return v, err == nil
+}
+
+// minLimit returns the lower limit, zero means no limit.
+func minLimit[T float64 | datasize.ByteSize](current, v T) T {
+	if current == 0 || v < current {
+		return v
+	}
+	return current
+}
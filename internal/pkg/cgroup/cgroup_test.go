@@ -0,0 +1,95 @@
+package cgroup
+
+import (
+	"runtime"
+	"testing"
+	"testing/fstest"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect_V2(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"proc/self/cgroup":                       {Data: []byte("0::/kubepods/pod1/container1\n")},
+		"sys/fs/cgroup/cgroup.controllers":       {Data: []byte("cpu memory\n")},
+		"sys/fs/cgroup/kubepods/pod1/cpu.max":    {Data: []byte("250000 100000\n")},
+		"sys/fs/cgroup/kubepods/pod1/memory.max": {Data: []byte("2147483648\n")},
+		"sys/fs/cgroup/kubepods/cpu.max":         {Data: []byte("max 100000\n")},
+		"sys/fs/cgroup/kubepods/memory.max":      {Data: []byte("max\n")},
+	}
+
+	// Limits of the parent cgroup are applied
+	assert.Equal(t, Limits{CPUs: 2.5, Memory: 2 * datasize.GB}, detect(fsys))
+}
+
+func TestDetect_V2_NoLimits(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"proc/self/cgroup":                 {Data: []byte("0::/\n")},
+		"sys/fs/cgroup/cgroup.controllers": {Data: []byte("cpu memory\n")},
+		"sys/fs/cgroup/cpu.max":            {Data: []byte("max 100000\n")},
+		"sys/fs/cgroup/memory.max":         {Data: []byte("max\n")},
+	}
+	assert.Equal(t, Limits{}, detect(fsys))
+}
+
+func TestDetect_V1(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"proc/self/cgroup": {Data: []byte("5:memory:/docker/abc\n4:cpu,cpuacct:/docker/abc\n0::/\n")},
+		// The process sees its own cgroup at the root of the mount
+		"sys/fs/cgroup/cpu/cpu.cfs_quota_us":         {Data: []byte("150000\n")},
+		"sys/fs/cgroup/cpu/cpu.cfs_period_us":        {Data: []byte("100000\n")},
+		"sys/fs/cgroup/memory/memory.limit_in_bytes": {Data: []byte("536870912\n")},
+	}
+	assert.Equal(t, Limits{CPUs: 1.5, Memory: 512 * datasize.MB}, detect(fsys))
+}
+
+func TestDetect_V1_NoLimits(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"proc/self/cgroup":                           {Data: []byte("5:memory:/\n4:cpu,cpuacct:/\n")},
+		"sys/fs/cgroup/cpu/cpu.cfs_quota_us":         {Data: []byte("-1\n")},
+		"sys/fs/cgroup/cpu/cpu.cfs_period_us":        {Data: []byte("100000\n")},
+		"sys/fs/cgroup/memory/memory.limit_in_bytes": {Data: []byte("9223372036854771712\n")},
+	}
+	assert.Equal(t, Limits{}, detect(fsys))
+}
+
+func TestDetect_NoCgroup(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, Limits{}, detect(fstest.MapFS{}))
+}
+
+func TestLimits_MaxProcs(t *testing.T) {
+	t.Parallel()
+
+	_, ok := Limits{}.MaxProcs()
+	assert.False(t, ok)
+
+	// At least one thread
+	procs, ok := Limits{CPUs: 0.5}.MaxProcs()
+	assert.Equal(t, 1, procs)
+	assert.Equal(t, runtime.NumCPU() > 1, ok)
+
+	// The quota is rounded down
+	procs, _ = Limits{CPUs: 2.9}.MaxProcs()
+	assert.Equal(t, 2, procs)
+}
+
+func TestLimits_SoftMemoryLimit(t *testing.T) {
+	t.Parallel()
+
+	_, ok := Limits{}.SoftMemoryLimit()
+	assert.False(t, ok)
+
+	limit, ok := Limits{Memory: 1000 * datasize.MB}.SoftMemoryLimit()
+	assert.True(t, ok)
+	assert.Equal(t, 900*datasize.MB, limit)
+}
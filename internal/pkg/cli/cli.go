@@ -1,21 +1,58 @@
 package cli
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"os"
-	"runtime/debug"
-	"runtime/pprof"
 
+	"github.com/c2h5oh/datasize"
 	"github.com/spf13/pflag"
 
+	"github.com/keboola/processor-split-table/internal/pkg/cgroup"
 	"github.com/keboola/processor-split-table/internal/pkg/cli/config"
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
 	"github.com/keboola/processor-split-table/internal/pkg/log"
+	"github.com/keboola/processor-split-table/internal/pkg/metrics"
+	"github.com/keboola/processor-split-table/internal/pkg/profiling"
 	"github.com/keboola/processor-split-table/internal/pkg/slicer"
+	"github.com/keboola/processor-split-table/internal/pkg/tracing"
 )
 
+const (
+	commandSlice   = "slice"
+	commandInspect = "inspect"
+	commandVerify  = "verify"
+	commandMerge   = "merge"
+	commandBatch   = "batch"
+)
+
+// Run the command specified by the first argument, "slicer [command] [flags]".
+// The flat invocation without a command, "slicer [flags]", is the slice command.
 func Run(logger log.Logger) error {
+	if len(os.Args) > 1 {
+		args := os.Args[2:]
+		switch os.Args[1] {
+		case commandSlice:
+			return runSlice(logger, args)
+		case commandInspect:
+			return runInspect(logger, args)
+		case commandVerify:
+			return runVerify(logger, args)
+		case commandMerge:
+			return runMerge(logger, args)
+		case commandBatch:
+			return runBatch(logger, args)
+		}
+	}
+
+	// Flat invocation, the program name and other positional arguments are ignored by the parser
+	return runSlice(logger, os.Args)
+}
+
+func runSlice(logger log.Logger, args []string) (err error) {
 	// Parse flags and ENVs
-	cfg, err := config.Parse(os.Args)
+	cfg, err := config.Parse(args)
 	if cfg.Help {
 		printUsage()
 		return pflag.ErrHelp
@@ -23,23 +60,57 @@ func Run(logger log.Logger) error {
 		return err
 	}
 
-	// Set soft memory limit (GOMEMLIMIT)
-	debug.SetMemoryLimit(int64(cfg.MemoryLimit.Bytes()))
+	// Create logger according to the configuration.
+	// STDOUT is reserved for the output table, if it is written to the stream.
+	logger = log.New(cfg.Log, cfg.OutPath == kbc.StdStreamPath)
+	defer func() { err = logError(logger, err) }()
+
+	// Set GOMAXPROCS and soft memory limit (GOMEMLIMIT), the cgroup limits are used by default
+	setLimits(logger, cfg.CgroupLimits, cfg.MemoryLimit)
 
-	// Dump configuration to STDOUT
+	// Dump configuration to STDOUT, the output can be used as the --config file.
+	// It is not written by the logger, so it is not modified by the log format.
 	if cfg.DumpConfig {
-		out, err := json.MarshalIndent(cfg, "", "  ")
+		out, err := json.MarshalIndent(cfg.Settings(), "", "  ")
 		if err != nil {
 			return err
 		}
-		logger.Info("Configuration: ", string(out))
+		dumpTo := os.Stdout
+		if cfg.OutPath == kbc.StdStreamPath {
+			dumpTo = os.Stderr
+		}
+		if _, err := dumpTo.Write(append(out, '\n')); err != nil {
+			return err
+		}
 	}
 
-	// Cpu profiling can be enabled by flag
-	if started, err := startCPUProfile(cfg.CPUProfileFile); err != nil {
-		return err
-	} else if started {
-		defer pprof.StopCPUProfile()
+	// Profiling can be enabled by flags
+	if cfg.Profiling.Enabled() {
+		stop, err := profiling.Start(logger, cfg.Profiling)
+		if err != nil {
+			return err
+		}
+		defer func() { err = errors.Join(err, stop()) }()
+	}
+
+	// Metrics can be enabled by flags
+	if cfg.Metrics.Enabled() {
+		m, stop, err := metrics.Start(logger, cfg.Metrics)
+		if err != nil {
+			return err
+		}
+		defer func() { err = errors.Join(err, stop()) }()
+		cfg.Table.Metrics = m
+	}
+
+	// Tracing can be enabled by flag
+	if cfg.Tracing.Enabled() {
+		provider, err := tracing.NewProvider(cfg.Tracing)
+		if err != nil {
+			return err
+		}
+		defer func() { err = errors.Join(err, provider.Shutdown(context.Background())) }()
+		cfg.TracerProvider = provider
 	}
 
 	// In CLI, the manifest must exist, if it is specified by the flag.
@@ -50,20 +121,159 @@ func Run(logger log.Logger) error {
 	return slicer.SliceTable(logger, cfg.Table)
 }
 
-func startCPUProfile(path string) (bool, error) {
-	if path != "" {
-		f, err := os.Create(path)
+func runBatch(logger log.Logger, args []string) (err error) {
+	// Parse flags and ENVs
+	cfg, err := config.ParseBatch(args)
+	if cfg.Help {
+		_, _ = os.Stderr.WriteString(config.BatchUsage())
+		return pflag.ErrHelp
+	} else if err != nil {
+		return err
+	}
+
+	// Create logger according to the configuration
+	logger = log.New(cfg.Log, false)
+	defer func() { err = logError(logger, err) }()
+
+	// Set GOMAXPROCS and soft memory limit (GOMEMLIMIT), the cgroup limits are used by default
+	setLimits(logger, cfg.CgroupLimits, cfg.MemoryLimit)
+
+	// Profiling can be enabled by flags
+	if cfg.Profiling.Enabled() {
+		stop, err := profiling.Start(logger, cfg.Profiling)
 		if err != nil {
-			return false, err
+			return err
 		}
-		err = pprof.StartCPUProfile(f)
+		defer func() { err = errors.Join(err, stop()) }()
+	}
+
+	// Load tables from the job file
+	tables, err := cfg.Tables()
+	if err != nil {
+		return err
+	}
+
+	// Metrics can be enabled by flags, they are shared by all tables
+	if cfg.Metrics.Enabled() {
+		m, stop, err := metrics.Start(logger, cfg.Metrics)
 		if err != nil {
-			return false, err
+			return err
+		}
+		defer func() { err = errors.Join(err, stop()) }()
+		for i := range tables {
+			tables[i].Metrics = m
 		}
-		return true, nil
 	}
 
-	return false, nil
+	// Tracing can be enabled by flag, the tracer provider is shared by all tables
+	if cfg.Tracing.Enabled() {
+		provider, err := tracing.NewProvider(cfg.Tracing)
+		if err != nil {
+			return err
+		}
+		defer func() { err = errors.Join(err, provider.Shutdown(context.Background())) }()
+		for i := range tables {
+			tables[i].TracerProvider = provider
+		}
+	}
+
+	// Slice tables
+	return slicer.SliceTables(logger, tables, int(cfg.Concurrency))
+}
+
+func runMerge(logger log.Logger, args []string) (err error) {
+	// Parse flags and ENVs
+	cfg, err := config.ParseMerge(args)
+	if cfg.Help {
+		_, _ = os.Stderr.WriteString(config.MergeUsage())
+		return pflag.ErrHelp
+	} else if err != nil {
+		return err
+	}
+
+	// Create logger according to the configuration
+	logger = log.New(cfg.Log, false)
+	defer func() { err = logError(logger, err) }()
+
+	// Set GOMAXPROCS and soft memory limit (GOMEMLIMIT), the cgroup limits are used by default
+	setLimits(logger, cfg.CgroupLimits, cfg.MemoryLimit)
+
+	// Merge table
+	return slicer.MergeTable(logger, cfg.MergedTable)
+}
+
+func runInspect(logger log.Logger, args []string) (err error) {
+	// Parse flags and ENVs
+	cfg, err := config.ParseInspect(commandInspect, args)
+	if cfg.Help {
+		_, _ = os.Stderr.WriteString(config.InspectUsage())
+		return pflag.ErrHelp
+	} else if err != nil {
+		return err
+	}
+
+	// Create logger according to the configuration
+	logger = log.New(cfg.Log, false)
+	defer func() { err = logError(logger, err) }()
+
+	// Set GOMAXPROCS and soft memory limit (GOMEMLIMIT), the cgroup limits are used by default
+	setLimits(logger, cfg.CgroupLimits, cfg.MemoryLimit)
+
+	// Inspect table
+	info, err := slicer.InspectTable(logger, cfg.InspectedTable)
+	if err != nil {
+		return err
+	}
+
+	// Print table info to STDOUT.
+	// It is not written by the logger, so it is not modified by the log format.
+	out, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(append(out, '\n'))
+	return err
+}
+
+func runVerify(logger log.Logger, args []string) (err error) {
+	// Parse flags and ENVs
+	cfg, err := config.ParseInspect(commandVerify, args)
+	if cfg.Help {
+		_, _ = os.Stderr.WriteString(config.VerifyUsage())
+		return pflag.ErrHelp
+	} else if err != nil {
+		return err
+	}
+
+	// Create logger according to the configuration
+	logger = log.New(cfg.Log, false)
+	defer func() { err = logError(logger, err) }()
+
+	// Set GOMAXPROCS and soft memory limit (GOMEMLIMIT), the cgroup limits are used by default
+	setLimits(logger, cfg.CgroupLimits, cfg.MemoryLimit)
+
+	// Verify table
+	return slicer.VerifyTable(logger, cfg.InspectedTable)
+}
+
+// logError logs the error by the configured logger, so also the error message has the configured format.
+// The returned error is only the exit code for main.go, the message is not logged again.
+func logError(logger log.Logger, err error) error {
+	var exitCodeErr *kbc.ExitCodeError
+	if err == nil || errors.As(err, &exitCodeErr) {
+		return err
+	}
+	logger.Error("Error: ", err.Error())
+	return kbc.ExitCodeErrorf(1, "%w", err)
+}
+
+// setLimits sets GOMAXPROCS and the soft memory limit, the cgroup limits are used as the defaults, if enabled.
+func setLimits(logger log.Logger, cgroupLimits bool, memoryLimit datasize.ByteSize) {
+	limits := cgroup.Limits{}
+	if cgroupLimits {
+		limits = cgroup.Detect()
+	}
+	cgroup.Apply(logger, limits, memoryLimit, config.DefaultMemoryLimit)
 }
 
 func printUsage() {
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/log"
+	"github.com/keboola/processor-split-table/internal/pkg/metrics"
+	"github.com/keboola/processor-split-table/internal/pkg/profiling"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer"
+	slicerConfig "github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/tracing"
+)
+
+const (
+	jobFileTablesKey = "tables"
+	batchUsageText   = `Usage of "slicer batch".
+
+  Slices many tables defined by the job file.
+  Tables are processed in parallel by a bounded pool of workers, a failed table does not stop the others.
+
+
+  Job file:
+    A YAML or JSON file with the "tables" list, keys of a table are flag names.
+    Keys "table-name", "table-input-path", "table-output-path" and "table-output-manifest-path" are required.
+    Other keys override flags of the command for the table, for example:
+
+    tables:
+      - table-name: orders
+        table-input-path: in/tables/orders.csv
+        table-input-manifest-path: in/tables/orders.csv.manifest
+        table-output-path: out/tables/orders.csv
+        table-output-manifest-path: out/tables/orders.csv.manifest
+        mode: rows
+
+
+  Exit code:
+    1, if at least one table failed.
+    Otherwise the highest "input-size-low-exit-code" of the skipped tables, if any, or 0.
+
+
+  Environment variables:
+    Each flag can be specified via an env variable with the "SLICER_" prefix.
+    For example --job-file flag can be specified via SLICER_JOB_FILE env.
+
+
+  All flags:
+`
+)
+
+// BatchConfig is configuration of the "batch" command.
+// The slicer configuration defines default values for all tables of the job file.
+type BatchConfig struct {
+	slicerConfig.Config `json:"config" mapstructure:",squash"`
+	Help                bool              `json:"help" mapstructure:"help"`
+	MemoryLimit         datasize.ByteSize `json:"memoryLimit" mapstructure:"memory-limit"` // 0 = auto, see cgroup.Apply
+	CgroupLimits        bool              `json:"cgroupLimits" mapstructure:"cgroup-limits"`
+	Profiling           profiling.Config  `json:"profiling" mapstructure:",squash"`
+	JobFile             string            `validate:"required" json:"jobFile" mapstructure:"job-file"`
+	Concurrency         uint32            `validate:"min=1" json:"concurrency" mapstructure:"concurrency"`
+	Log                 log.Config        `json:"log" mapstructure:",squash"`
+	Metrics             metrics.Config    `json:"metrics" mapstructure:",squash"`
+	Tracing             tracing.Config    `json:"tracing" mapstructure:",squash"`
+}
+
+func DefaultBatch() BatchConfig {
+	cfg := BatchConfig{}
+	cfg.Config = slicerConfig.Default()
+	cfg.CgroupLimits = true
+	cfg.Concurrency = 2
+	cfg.Log = log.DefaultConfig()
+	cfg.Metrics = metrics.DefaultConfig()
+	return cfg
+}
+
+func ParseBatch(args []string) (BatchConfig, error) {
+	cfg := DefaultBatch()
+	binder, err := parse(batchFlags(), args, &cfg)
+	if err == nil {
+		cfg.Explicit = explicitSettings(binder.IsSet)
+	}
+	return cfg, err
+}
+
+func BatchUsage() string {
+	var b strings.Builder
+	b.WriteString(batchUsageText)
+	b.WriteString(batchFlags().FlagUsages())
+	b.WriteString("\n")
+	return b.String()
+}
+
+// Tables loads definitions of the tables from the job file.
+// Each table is decoded and validated separately, missing keys are taken from the BatchConfig.
+func (c BatchConfig) Tables() ([]slicer.Table, error) {
+	// Read job file
+	file := viper.New()
+	file.SetConfigFile(c.JobFile)
+	if err := file.ReadInConfig(); err != nil {
+		return nil, kbc.UserErrorf(`cannot read job file "%s": %w`, c.JobFile, err)
+	}
+	for _, key := range file.AllKeys() {
+		if key != jobFileTablesKey {
+			return nil, kbc.UserErrorf(`unexpected key "%s" in the job file "%s"`, key, c.JobFile)
+		}
+	}
+	items, ok := file.Get(jobFileTablesKey).([]any)
+	if !ok || len(items) == 0 {
+		return nil, kbc.UserErrorf(`the job file "%s" does not define any table`, c.JobFile)
+	}
+
+	// Keys of a table are flag names, default values are taken from the BatchConfig
+	keys := pflag.NewFlagSet("table", pflag.ContinueOnError)
+	addTableFlags(keys, slicer.Table{Config: c.Config})
+	addSlicerFlags(keys, c.Config)
+	defaults := flagValues(keys)
+
+	var tables []slicer.Table
+	outPaths := make(map[string]bool)
+	for i, item := range items {
+		values, ok := item.(map[string]any)
+		if !ok {
+			return nil, kbc.UserErrorf(`table %d in the job file "%s" is not an object`, i+1, c.JobFile)
+		}
+
+		// Merge values with defaults
+		binder := viper.New()
+		for key, value := range defaults {
+			binder.SetDefault(key, value)
+		}
+		for key, value := range values {
+			if keys.Lookup(key) == nil {
+				return nil, kbc.UserErrorf(`unexpected key "%s" in the table %d in the job file "%s"`, key, i+1, c.JobFile)
+			}
+			binder.Set(key, value)
+		}
+
+		// Decode and validate table
+		table := slicer.Table{}
+		if err := decode(binder, &table); err != nil {
+			return nil, kbc.UserErrorf(`table %d in the job file "%s" is not valid: %w`, i+1, c.JobFile, err)
+		}
+
+		// Standard streams cannot be shared by the tables processed in parallel
+		if table.InPath == kbc.StdStreamPath || table.OutPath == kbc.StdStreamPath {
+			return nil, kbc.UserErrorf(`table "%s": the standard input and output cannot be used in the batch mode`, table.Name)
+		}
+
+		// Each table must be written to a different directory
+		if outPaths[table.OutPath] {
+			return nil, kbc.UserErrorf(`table "%s": the output path "%s" is used by another table`, table.Name, table.OutPath)
+		}
+		outPaths[table.OutPath] = true
+
+		// A setting is explicit, if it is set for the table or for all tables
+		tableExplicit := explicitSettings(func(key string) bool {
+			_, found := values[key]
+			return found
+		})
+		table.Explicit = slicerConfig.PoolSettings{
+			GzipConcurrency: c.Explicit.GzipConcurrency || tableExplicit.GzipConcurrency,
+			GzipBlockSize:   c.Explicit.GzipBlockSize || tableExplicit.GzipBlockSize,
+			BufferSize:      c.Explicit.BufferSize || tableExplicit.BufferSize,
+			AheadBlocks:     c.Explicit.AheadBlocks || tableExplicit.AheadBlocks,
+			AheadBlockSize:  c.Explicit.AheadBlockSize || tableExplicit.AheadBlockSize,
+			SpillBufferSize: c.Explicit.SpillBufferSize || tableExplicit.SpillBufferSize,
+		}
+
+		// In CLI, the manifest must exist, if it is specified
+		table.InManifestMustExists = true
+		tables = append(tables, table)
+	}
+
+	return tables, nil
+}
+
+func batchFlags() *pflag.FlagSet {
+	cfg := DefaultBatch()
+
+	f := pflag.NewFlagSet("slicer batch", pflag.ContinueOnError)
+	f.Bool("help", false, "Print help.")
+	addConfigFlag(f)
+	f.String("memory-limit", cfg.MemoryLimit.String(), "Soft memory limit, GOMEMLIMIT, shared by all tables, "+memoryLimitAuto)
+	addCgroupFlag(f, cfg.CgroupLimits)
+	addProfilingFlags(f, cfg.Profiling)
+	addLogFlags(f, cfg.Log)
+	addMetricsFlags(f, cfg.Metrics)
+	addTracingFlags(f, cfg.Tracing)
+	f.String("job-file", cfg.JobFile, `Path to a YAML or JSON file with the "tables" list.`)
+	f.Uint32("concurrency", cfg.Concurrency, "Number of tables processed in parallel, CPU threads of the auto gzip concurrency are divided between them.")
+	addSlicerFlags(f, cfg.Config)
+	return f
+}
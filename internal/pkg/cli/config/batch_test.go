@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/keboola/processor-split-table/internal/pkg/slicer"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+)
+
+func TestBatchUsage(t *testing.T) {
+	t.Parallel()
+	assert.NotEmpty(t, BatchUsage()) // asserted in the "cli/batch-help" E2E test
+}
+
+func TestParseBatch_Help(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := ParseBatch([]string{"--help"})
+	require.Error(t, err)
+	assert.True(t, cfg.Help)
+}
+
+func TestParseBatch_Empty(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseBatch([]string{})
+	if assert.Error(t, err) {
+		assert.Equal(t, strings.TrimSpace(`
+configuration is not valid:
+- job-file is a required flag
+`), err.Error())
+	}
+}
+
+func TestParseBatch_Full(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := ParseBatch([]string{
+		"--job-file", "jobs.yaml",
+		"--concurrency", "4",
+		"--mode", "rows",
+		"--rows-per-slice", "100",
+		"--memory-limit", "1GB",
+	})
+	assert.NoError(t, err)
+
+	expected := DefaultBatch()
+	expected.JobFile = "jobs.yaml"
+	expected.Concurrency = 4
+	expected.Mode = config.ModeRows
+	expected.RowsPerSlice = 100
+	expected.MemoryLimit = 1 * datasize.GB
+	expected.SortBy = []string{}         // empty flag value
+	expected.ZoneMapColumns = []string{} // empty flag value
+	assert.Equal(t, expected, cfg)
+}
+
+func TestBatchConfig_Tables(t *testing.T) {
+	t.Parallel()
+
+	path := writeJobFile(t, `
+tables:
+  - table-name: first
+    table-input-path: in/tables/first.csv
+    table-output-path: out/tables/first.csv
+    table-output-manifest-path: out/tables/first.csv.manifest
+  - table-name: second
+    table-input-path: in/tables/second.csv
+    table-input-manifest-path: in/tables/second.csv.manifest
+    table-output-path: out/tables/second.csv
+    table-output-manifest-path: out/tables/second.csv.manifest
+    rows-per-slice: 50
+    sort-by: [id]
+    spill-buffer-size: 64MB
+`)
+
+	cfg, err := ParseBatch([]string{"--job-file", path, "--mode", "rows", "--rows-per-slice", "100", "--gzip-block-size", "1MB"})
+	require.NoError(t, err)
+	tables, err := cfg.Tables()
+	require.NoError(t, err)
+
+	// Settings of the flags are explicit, even if they have the default value
+	assert.Equal(t, config.PoolSettings{GzipBlockSize: true}, cfg.Explicit)
+
+	// Defaults are taken from the flags
+	first := slicer.Table{Config: cfg.Config}
+	first.Name = "first"
+	first.InPath = "in/tables/first.csv"
+	first.OutPath = "out/tables/first.csv"
+	first.OutManifestPath = "out/tables/first.csv.manifest"
+	first.InManifestMustExists = true
+
+	// Flags are overridden by the table keys
+	second := slicer.Table{Config: cfg.Config}
+	second.Name = "second"
+	second.InPath = "in/tables/second.csv"
+	second.InManifestPath = "in/tables/second.csv.manifest"
+	second.OutPath = "out/tables/second.csv"
+	second.OutManifestPath = "out/tables/second.csv.manifest"
+	second.InManifestMustExists = true
+	second.RowsPerSlice = 50
+	second.SortBy = []string{"id"}
+	second.SpillBufferSize = 64 * datasize.MB
+	second.Explicit.SpillBufferSize = true
+
+	assert.Equal(t, []slicer.Table{first, second}, tables)
+}
+
+func TestBatchConfig_Tables_Invalid(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct{ name, content, expected string }{
+		{
+			name:     "no tables",
+			content:  "tables: []\n",
+			expected: `the job file "%s" does not define any table`,
+		},
+		{
+			name:     "unexpected key",
+			content:  "tables: []\nfoo: bar\n",
+			expected: `unexpected key "foo" in the job file "%s"`,
+		},
+		{
+			name:     "unexpected table key",
+			content:  "tables:\n  - table-nam: first\n",
+			expected: `unexpected key "table-nam" in the table 1 in the job file "%s"`,
+		},
+		{
+			name:    "missing table key",
+			content: "tables:\n  - table-name: first\n    table-input-path: in.csv\n    table-output-path: out.csv\n",
+			expected: `table 1 in the job file "%s" is not valid: configuration is not valid:
+- table-output-manifest-path is a required flag`,
+		},
+		{
+			name:     "standard stream",
+			content:  "tables:\n  - table-name: first\n    table-input-path: '-'\n    table-output-path: out.csv\n    table-output-manifest-path: out.csv.manifest\n",
+			expected: `table "first": the standard input and output cannot be used in the batch mode`,
+		},
+		{
+			name:     "duplicate output",
+			content:  "tables:\n  - table-name: first\n    table-input-path: a.csv\n    table-output-path: out.csv\n    table-output-manifest-path: a.manifest\n  - table-name: second\n    table-input-path: b.csv\n    table-output-path: out.csv\n    table-output-manifest-path: b.manifest\n",
+			expected: `table "second": the output path "out.csv" is used by another table`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := writeJobFile(t, tc.content)
+			cfg, err := ParseBatch([]string{"--job-file", path})
+			require.NoError(t, err)
+
+			_, err = cfg.Tables()
+			expected := tc.expected
+			if strings.Contains(expected, "%s") {
+				expected = fmt.Sprintf(expected, path)
+			}
+			if assert.Error(t, err) {
+				assert.Equal(t, expected, err.Error())
+			}
+		})
+	}
+}
+
+func writeJobFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jobs.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(strings.TrimLeft(content, "\n")), 0o600))
+	return path
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/c2h5oh/datasize"
@@ -15,13 +16,35 @@ import (
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/log"
+	"github.com/keboola/processor-split-table/internal/pkg/metrics"
+	"github.com/keboola/processor-split-table/internal/pkg/profiling"
 	"github.com/keboola/processor-split-table/internal/pkg/slicer"
 	slicerConfig "github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/tracing"
 )
 
+// DefaultMemoryLimit is used, if the memory limit is not set and there is no cgroup memory limit.
+const DefaultMemoryLimit = 512 * datasize.MB
+
 const (
-	ENVPrefix = "SLICER"
-	usageText = `Usage of "slicer".
+	ENVPrefix       = "SLICER"
+	configFlag      = "config"
+	memoryLimitAuto = `"0B" means the GOMEMLIMIT env, 90% of the cgroup memory limit, or "512MB".`
+	usageText       = `Usage of "slicer".
+
+  Commands:
+      slice
+        Slices the input table, it is the default command, "slicer [flags]" is the same as "slicer slice [flags]".
+      inspect
+        Prints slices, sizes, compression, dialect and columns of the input table, see "slicer inspect --help".
+      verify
+        Reads and validates all rows of the input table without any output, see "slicer verify --help".
+      merge
+        Merges slices of the input table to one CSV file, see "slicer merge --help".
+      batch
+        Slices many tables defined by a job file in parallel, see "slicer batch --help".
 
   Modes via --mode:
       bytes
@@ -41,6 +64,7 @@ const (
         Table name for logging purposes.
     --table-input-path
         Path to the input table, either a file or a directory with slices.
+        Use "-" to read the CSV table from STDIN, the compression is detected by the gzip magic bytes.
     --table-input-manifest-path
         Path to the manifest of the input table.
         It is used to get "delimiter" and "enclosure" fields, if any.
@@ -48,11 +72,19 @@ const (
     --table-output-path
         Directory where the slices of the output table will be written.
         If it does not exist, it will be created, but the parent directory must exist.
+        Use "-" to write all rows to STDOUT as a single stream, log messages are written to STDERR.
     --table-output-manifest-path
         Path where the output manifest will be written.
         The parent directory must exist.
         The output manifest is a copy of the input manifest.
         The "columns" field is set from the CSV header, if it is missing.
+    --table-output-parts-metadata-path
+        Path where the metadata of the output slices will be written, if any.
+        It contains rows and bytes of each slice, the min/max key, if the table is sorted,
+        and the min/max values of the --zone-map-columns, if any.
+    --table-output-profile-path
+        Path where the statistics of the output columns will be written, if any.
+        It contains number of nulls, estimated number of distinct values, min/max value and max length of each column.
 
 
   Environment variables:
@@ -60,52 +92,105 @@ const (
     For example --bytes-per-slice flag can be specified via SLICER_BYTES_PER_SLICE env.
 
 
+  Config file:
+    All flags can be loaded from a YAML or JSON file specified by the --config flag, keys are flag names.
+    Precedence: flags > ENVs > the config file > defaults.
+    The output of the --dump-config flag can be used as the config file.
+
+
   All flags:
 `
 )
 
 type Config struct {
-	slicer.Table   `json:"table"  mapstructure:",squash"`
-	Help           bool              `json:"help" mapstructure:"help"`
-	DumpConfig     bool              `json:"dumpConfig" mapstructure:"dump-config"`
-	MemoryLimit    datasize.ByteSize `validate:"required" json:"memoryLimit" mapstructure:"memory-limit"`
-	CPUProfileFile string            `json:"cpuProfile" mapstructure:"cpuprofile"`
+	slicer.Table `json:"table"  mapstructure:",squash"`
+	Help         bool              `json:"help" mapstructure:"help"`
+	DumpConfig   bool              `json:"dumpConfig" mapstructure:"dump-config"`
+	MemoryLimit  datasize.ByteSize `json:"memoryLimit" mapstructure:"memory-limit"` // 0 = auto, see cgroup.Apply
+	CgroupLimits bool              `json:"cgroupLimits" mapstructure:"cgroup-limits"`
+	Profiling    profiling.Config  `json:"profiling" mapstructure:",squash"`
+	Log          log.Config        `json:"log" mapstructure:",squash"`
+	Metrics      metrics.Config    `json:"metrics" mapstructure:",squash"`
+	Tracing      tracing.Config    `json:"tracing" mapstructure:",squash"`
 }
 
 func Default() Config {
 	cfg := Config{}
 	cfg.Config = slicerConfig.Default()
-	cfg.MemoryLimit = 512 * datasize.MB
+	cfg.CgroupLimits = true
+	cfg.Log = log.DefaultConfig()
+	cfg.Metrics = metrics.DefaultConfig()
 	return cfg
 }
 
 func Parse(args []string) (Config, error) {
 	cfg := Default()
+	binder, err := parse(flags(Default()), args, &cfg)
+	if err == nil {
+		cfg.Explicit = explicitSettings(binder.IsSet)
+	}
+	return cfg, err
+}
 
+// Settings returns values of the configuration by flag names, so the map encoded to JSON can be used as the --config file.
+// Flags that control the CLI itself are omitted.
+func (c Config) Settings() map[string]any {
+	return flagValues(flags(c))
+}
+
+// parse flags, ENVs and the optional config file to the config structure and validate it.
+// Precedence: flags > ENVs > the config file > defaults.
+// The binder is returned, so the caller can check which keys are set.
+func parse(f *pflag.FlagSet, args []string, cfg any) (*viper.Viper, error) {
 	// Parse flags
-	f := flags()
 	if err := f.Parse(args); err != nil {
-		return cfg, fmt.Errorf("cannot parse flags: %w", err)
+		return nil, fmt.Errorf("cannot parse flags: %w", err)
+	}
+
+	// Bind flags to the config structure
+	binder := viper.New()
+	binder.AutomaticEnv()
+	binder.SetEnvPrefix(ENVPrefix)
+	binder.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	if err := binder.BindPFlags(f); err != nil {
+		return nil, fmt.Errorf("cannot bind flags: %w", err)
 	}
 
+	// Load config file, it can be specified also by the ENV
+	if path := binder.GetString(configFlag); path != "" {
+		if err := readConfigFile(binder, f, path); err != nil {
+			return nil, err
+		}
+	}
+
+	return binder, decode(binder, cfg)
+}
+
+// explicitSettings returns the buffer pool settings set by the user, they are not reduced by the memory plan.
+func explicitSettings(isSet func(key string) bool) slicerConfig.PoolSettings {
+	return slicerConfig.PoolSettings{
+		GzipConcurrency: isSet("gzip-concurrency"),
+		GzipBlockSize:   isSet("gzip-block-size"),
+		BufferSize:      isSet("buffer-size"),
+		AheadBlocks:     isSet("ahead-blocks"),
+		AheadBlockSize:  isSet("ahead-block-size"),
+		SpillBufferSize: isSet("spill-buffer-size"),
+	}
+}
+
+// decode values from the binder to the config structure and validate it.
+func decode(binder *viper.Viper, cfg any) error {
 	// Define mapstructure hooks
 	hooks := viper.DecodeHook(
 		mapstructure.ComposeDecodeHookFunc(
 			mapstructure.TextUnmarshallerHookFunc(),
 			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
 		),
 	)
 
-	// Bind flags to the config structure
-	binder := viper.New()
-	binder.AutomaticEnv()
-	binder.SetEnvPrefix(ENVPrefix)
-	binder.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
-	if err := binder.BindPFlags(f); err != nil {
-		return cfg, fmt.Errorf("cannot bind flags: %w", err)
-	}
-	if err := binder.Unmarshal(&cfg, hooks); err != nil {
-		return cfg, fmt.Errorf("cannot unmarshal flags: %w", err)
+	if err := binder.Unmarshal(cfg, hooks); err != nil {
+		return fmt.Errorf("cannot unmarshal flags: %w", err)
 	}
 
 	// Create validator
@@ -126,10 +211,10 @@ func Parse(args []string) (Config, error) {
 	lang := en.New()
 	trans, _ := ut.New(lang, lang).GetTranslator("en")
 	if err := en_translations.RegisterDefaultTranslations(v, trans); err != nil {
-		return cfg, err
+		return err
 	}
 	if err := trans.Add("required", `{0} is a required flag`, true); err != nil {
-		return cfg, err
+		return err
 	}
 
 	// Validate config
@@ -145,22 +230,132 @@ func Parse(args []string) (Config, error) {
 			}
 			err = errors.New(b.String())
 		}
-		return cfg, fmt.Errorf(`configuration is not valid:%w`, err)
+		return fmt.Errorf(`configuration is not valid:%w`, err)
 	}
 
-	return cfg, nil
+	return nil
+}
+
+// addConfigFlag adds the --config flag, the config file is supported by all commands.
+func addConfigFlag(f *pflag.FlagSet) {
+	f.String(configFlag, "", "Path to a YAML or JSON config file, keys are flag names, flags and ENVs take precedence.")
+}
+
+// readConfigFile merges the YAML or JSON config file, the format is detected by the extension.
+// Each key must be a flag name, so a typo is not silently ignored.
+func readConfigFile(binder *viper.Viper, f *pflag.FlagSet, path string) error {
+	binder.SetConfigFile(path)
+	if err := binder.ReadInConfig(); err != nil {
+		return kbc.UserErrorf(`cannot read config file "%s": %w`, path, err)
+	}
+
+	// Check keys
+	file := viper.New()
+	file.SetConfigFile(path)
+	if err := file.ReadInConfig(); err != nil {
+		return kbc.UserErrorf(`cannot read config file "%s": %w`, path, err)
+	}
+	for _, key := range file.AllKeys() {
+		if flag := f.Lookup(key); flag == nil || key == configFlag {
+			return kbc.UserErrorf(`unexpected key "%s" in the config file "%s"`, key, path)
+		}
+	}
+
+	return nil
+}
+
+// flagValues returns values of the flags by flag names, flags that control the CLI itself are omitted.
+func flagValues(f *pflag.FlagSet) map[string]any {
+	out := make(map[string]any)
+	f.VisitAll(func(flag *pflag.Flag) {
+		switch flag.Name {
+		case configFlag, "help", "dump-config":
+			return
+		}
+		if flag.Value.Type() == "stringSlice" {
+			out[flag.Name], _ = f.GetStringSlice(flag.Name)
+		} else {
+			out[flag.Name] = typedValue(flag.Value.Type(), flag.Value.String())
+		}
+	})
+	return out
 }
 
 func Usage() string {
 	var b strings.Builder
 	b.WriteString(usageText)
-	b.WriteString(flags().FlagUsages())
+	b.WriteString(flags(Default()).FlagUsages())
 	b.WriteString("\n")
 	return b.String()
 }
 
-func flags() *pflag.FlagSet {
-	cfg := Default()
+// flags defines flags, default values are taken from the cfg.
+func flags(cfg Config) *pflag.FlagSet {
+	f := pflag.NewFlagSet("slicer", pflag.ContinueOnError)
+	f.Bool("help", false, "Print help.")
+	addConfigFlag(f)
+	f.String("memory-limit", cfg.MemoryLimit.String(), "Soft memory limit, GOMEMLIMIT, "+memoryLimitAuto)
+	addCgroupFlag(f, cfg.CgroupLimits)
+	f.Bool("dump-config", cfg.DumpConfig, "Print all parameters to the STDOUT as JSON, it can be used as the --config file.")
+	addProfilingFlags(f, cfg.Profiling)
+	addLogFlags(f, cfg.Log)
+	addMetricsFlags(f, cfg.Metrics)
+	addTracingFlags(f, cfg.Tracing)
+	addTableFlags(f, cfg.Table)
+	addSlicerFlags(f, cfg.Config)
+	return f
+}
+
+// addCgroupFlag adds the flag of the cgroup limits detection, it is supported by all commands.
+func addCgroupFlag(f *pflag.FlagSet, enabled bool) {
+	f.Bool("cgroup-limits", enabled, "Use the cgroup CPU quota and memory limit as the defaults of GOMAXPROCS, the gzip concurrency and the memory limit.")
+}
+
+// addLogFlags adds flags of the logger, they are supported by all commands.
+func addLogFlags(f *pflag.FlagSet, cfg log.Config) {
+	f.String("log-format", cfg.Format, `Format of the log messages, "console" or "json" with structured fields.`)
+	f.String("log-level", cfg.Level, `Minimum level of the log messages, "debug", "info", "warn" or "error".`)
+}
+
+// addProfilingFlags adds flags of the Go runtime profiles, they are supported by the slicing commands.
+func addProfilingFlags(f *pflag.FlagSet, cfg profiling.Config) {
+	f.String("cpuprofile", cfg.CPUProfile, "Write the CPU profile to the specified file.")
+	f.String("memprofile", cfg.HeapProfile, "Write the heap profile to the specified file, at the end of the run.")
+	f.String("allocsprofile", cfg.AllocsProfile, "Write the allocs profile to the specified file, at the end of the run.")
+	f.String("blockprofile", cfg.BlockProfile, "Write the goroutine blocking profile to the specified file, at the end of the run.")
+	f.String("mutexprofile", cfg.MutexProfile, "Write the mutex contention profile to the specified file, at the end of the run.")
+	f.String("trace", cfg.Trace, "Write the execution trace to the specified file.")
+	f.String("pprof-addr", cfg.PprofAddr, `Serve pprof profiles on the address, for example "localhost:6060", path "/debug/pprof/".`)
+}
+
+// addMetricsFlags adds flags of the Prometheus metrics, they are supported by the slicing commands.
+func addMetricsFlags(f *pflag.FlagSet, cfg metrics.Config) {
+	f.String("metrics-addr", cfg.Addr, `Serve Prometheus metrics on the address, for example ":9090", path "/metrics".`)
+	f.String("metrics-file", cfg.File, "Write Prometheus metrics periodically to the node-exporter textfile.")
+	f.Duration("metrics-interval", cfg.Interval, "Interval of writing the metrics file.")
+}
+
+// addTracingFlags adds flags of the OpenTelemetry tracing, they are supported by the slicing commands.
+func addTracingFlags(f *pflag.FlagSet, cfg tracing.Config) {
+	f.String("otlp-endpoint", cfg.Endpoint, `Export OpenTelemetry spans to the OTLP HTTP endpoint, for example "http://localhost:4318".`)
+}
+
+// addTableFlags adds flags defining one table, in the batch mode they are used as keys in the job file.
+func addTableFlags(f *pflag.FlagSet, table slicer.Table) {
+	f.String("table-name", table.Name, "Table name for logging purposes.")
+	f.String("table-input-path", table.InPath, "Path to the input table, either a file or a directory with slices.")
+	f.String("table-input-manifest-path", table.InManifestPath, "Path to the manifest describing the input table, if any.")
+	f.String("table-output-path", table.OutPath, "Directory where the slices of the output table will be written.")
+	f.String("table-output-manifest-path", table.OutManifestPath, "Path where the output manifest will be written.")
+	f.String("table-output-parts-metadata-path", table.OutPartsMetadataPath, "Path where the metadata of the output slices will be written, if any.")
+	f.String("table-output-profile-path", table.OutProfilePath, "Path where the statistics of the output columns will be written, if any.")
+	f.String("result-file", table.OutResultPath, "Path where the result of the table will be written as JSON, if any.")
+	f.String("expected-input-size", table.ExpectedInputSize.String(), "Expected size of the input table, used if the size is unknown, for example, of STDIN or a named pipe.")
+	f.Uint32("input-size-low-exit-code", table.InputSizeLowExitCode, "If specified, the skipped tables is not be copied, but the program exits with the exit code.")
+}
+
+// addSlicerFlags adds flags of the slicer configuration.
+func addSlicerFlags(f *pflag.FlagSet, cfg slicerConfig.Config) {
 	modes := fmt.Sprintf(
 		`%s, %s, or %s`,
 		slicerConfig.ModeBytes.String(),
@@ -168,18 +363,6 @@ func flags() *pflag.FlagSet {
 		slicerConfig.ModeSlices.String(),
 	)
 
-	f := pflag.NewFlagSet("slicer", pflag.ContinueOnError)
-	f.Bool("help", false, "Print help.")
-	f.String("memory-limit", cfg.MemoryLimit.String(), "Soft memory limit, GOMEMLIMIT.")
-	f.Bool("dump-config", cfg.DumpConfig, "Print all parameters to the STDOUT.")
-	f.String("cpuprofile", cfg.CPUProfileFile, "Write the CPU profile to the specified file.")
-
-	f.String("table-name", cfg.Name, "Table name for logging purposes.")
-	f.String("table-input-path", cfg.InPath, "Path to the input table, either a file or a directory with slices.")
-	f.String("table-input-manifest-path", cfg.InManifestPath, "Path to the manifest describing the input table, if any.")
-	f.String("table-output-path", cfg.OutPath, "Directory where the slices of the output table will be written.")
-	f.String("table-output-manifest-path", cfg.OutManifestPath, "Path where the output manifest will be written.")
-
 	f.String("mode", cfg.Mode.String(), modes)
 	f.String("bytes-per-slice", cfg.BytesPerSlice.String(), `Maximum size of a slice, for "bytes"" mode.`)
 	f.Uint64("rows-per-slice", cfg.RowsPerSlice, `Maximum number of rows per slice, for "rows" mode.`)
@@ -194,8 +377,9 @@ func flags() *pflag.FlagSet {
 	f.Uint32("ahead-blocks", cfg.AheadBlocks, "Number of blocks read ahead from an input slice, 0 disables read-ahead.")
 	f.String("ahead-block-size", cfg.AheadBlockSize.String(), "Size of a one read ahead input block.")
 
+	f.String("input-format", cfg.InputFormat.String(), `Format of the input table, "csv", "parquet", "jsonl" or "auto" detected by the extension.`)
+
 	f.String("input-size-threshold", cfg.InputSizeThreshold.String(), "At least one slice must exceed the threshold, otherwise the table is copied without modification.")
-	f.Uint32("input-size-low-exit-code", cfg.InputSizeLowExitCode, "If specified, the skipped tables is not be copied, but the program exits with the exit code.")
 
 	f.Bool("gzip", cfg.Gzip, "Enable gzip compression for slices.")
 	f.Int("gzip-level", cfg.GzipLevel, "GZIP compression level, range: 1 best speed - 9 best compression.")
@@ -203,5 +387,47 @@ func flags() *pflag.FlagSet {
 	f.String("gzip-block-size", cfg.GzipBlockSize.String(), "Size of the one gzip block; allocated memory = concurrency * block size.")
 	f.String("buffer-size", cfg.BufferSize.String(), "Output buffer size when gzip compression is disabled.")
 
-	return f
+	f.String("output-format", cfg.OutputFormat.String(), `Format of the output slices, "csv", "parquet", "jsonl" or "arrow".`)
+	f.String("output-types", cfg.OutputTypes.String(), `Types of the columns in the "parquet", "jsonl" and "arrow" formats, "string" or "inferred" from the input values.`)
+	f.String("parquet-compression", cfg.ParquetCompression.String(), `Compression codec of the "parquet" format, "none", "snappy" or "zstd".`)
+
+	f.String("deduplication", cfg.Deduplication.String(), `Keep only the "first" or the "last" occurrence of each manifest "primary_key", or "none".`)
+	f.StringSlice("sort-by", cfg.SortBy, "Sort the table by the columns, the key range of each slice is disjoint.")
+	f.StringSlice("zone-map-columns", cfg.ZoneMapColumns, "Write min/max values of the columns in each slice to the parts metadata.")
+	f.String("spill-buffer-size", cfg.SpillBufferSize.String(), "Maximum size of rows held in memory by the deduplication and the sort, the rest is spilled to disk.")
+	f.String("temp-dir", cfg.TempDir, "Directory for spilled rows, the system temp dir is used if empty.")
+	f.Bool("infer-types", cfg.InferTypes, `Infer types of the columns and write them to the output manifest "column_metadata".`)
+	f.String("disk-space-check", cfg.DiskSpaceCheck.String(), `If the estimated output size exceeds the free disk space, "fail" before slicing, "warn", or "none".`)
+
+	f.Float64("sample-fraction", cfg.Sample.Fraction, "Keep only a random fraction of the rows, range: 0 disabled - 1 all rows.")
+	f.Uint64("sample-rows", cfg.Sample.Rows, "Keep only a random sample of the number of rows, 0 disabled, the rows must fit into the --spill-buffer-size.")
+	f.Int64("sample-seed", cfg.Sample.Seed, "Seed of the random sample, the same seed and input produce the same sample.")
+}
+
+// typedValue converts the string value to the type of the flag, so it is encoded to JSON as a number, a bool or an array.
+func typedValue(flagType, value string) any {
+	switch flagType {
+	case "int", "int64":
+		if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return number
+		}
+	case "uint32", "uint64":
+		if number, err := strconv.ParseUint(value, 10, 64); err == nil {
+			return number
+		}
+	case "float64":
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	case "bool":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case "stringSlice":
+		if value == "" {
+			return []string{}
+		}
+		return strings.Split(value, ",")
+	}
+	return value
 }
@@ -1,6 +1,10 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -9,7 +13,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/keboola/processor-split-table/internal/pkg/log"
+	"github.com/keboola/processor-split-table/internal/pkg/metrics"
+	"github.com/keboola/processor-split-table/internal/pkg/profiling"
 	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/tracing"
 )
 
 func TestUsage(t *testing.T) {
@@ -56,6 +64,8 @@ func TestParseConfig_Minimal(t *testing.T) {
 	expected.InPath = "in/tables/my.csv"
 	expected.OutPath = "out/tables/my.csv"
 	expected.OutManifestPath = "out/tables/my.csv.manifest"
+	expected.SortBy = []string{}         // empty flag value
+	expected.ZoneMapColumns = []string{} // empty flag value
 	assert.Equal(t, expected, cfg)
 }
 
@@ -66,23 +76,52 @@ func TestParseConfig_Full(t *testing.T) {
 		"--buffer-size", "123KB",
 		"--bytes-per-slice", "1MB",
 		"--cpuprofile", "cpu.out",
+		"--memprofile", "heap.out",
+		"--allocsprofile", "allocs.out",
+		"--blockprofile", "block.out",
+		"--mutexprofile", "mutex.out",
+		"--trace", "trace.out",
+		"--pprof-addr", "localhost:6060",
+		"--deduplication", "last",
+		"--disk-space-check", "fail",
+		"--expected-input-size", "2GB",
+		"--infer-types",
+		"--input-format", "jsonl",
 		"--input-size-threshold", "10MB",
 		"--gzip=false",
 		"--gzip-block-size", "2MB",
 		"--gzip-concurrency", "5",
 		"--gzip-level", "4",
 		"--memory-limit", "128MB",
+		"--cgroup-limits=false",
 		"--min-bytes-per-slice", "3MB",
 		"--log-interval-multiplier", "2",
 		"--log-interval-initial", "30s",
 		"--log-interval-maximum", "40s",
+		"--log-format", "json",
+		"--log-level", "debug",
+		"--metrics-addr", ":9090",
+		"--metrics-file", "/var/lib/node-exporter/slicer.prom",
+		"--metrics-interval", "1m",
+		"--otlp-endpoint", "http://localhost:4318",
 		"--mode", "rows",
 		"--number-of-slices", "456",
+		"--output-format", "parquet",
+		"--output-types", "inferred",
+		"--parquet-compression", "zstd",
 		"--rows-per-slice", "789",
+		"--sample-rows", "100",
+		"--sample-seed", "42",
+		"--sort-by", "id,name",
+		"--spill-buffer-size", "16MB",
 		"--table-name", "my-table",
 		"--table-input-path", "in/tables/my.csv",
 		"--table-output-path", "out/tables/my.csv",
 		"--table-output-manifest-path", "out/tables/my.csv.manifest",
+		"--table-output-parts-metadata-path", "out/tables/my.csv.parts.json",
+		"--table-output-profile-path", "out/tables/my.csv.profile.json",
+		"--temp-dir", "/tmp/slicer",
+		"--zone-map-columns", "date,price",
 	})
 	assert.NoError(t, err)
 
@@ -90,7 +129,21 @@ func TestParseConfig_Full(t *testing.T) {
 
 	expected.BufferSize = 123 * datasize.KB
 	expected.BytesPerSlice = 1 * datasize.MB
-	expected.CPUProfileFile = "cpu.out"
+	expected.Profiling = profiling.Config{
+		CPUProfile:    "cpu.out",
+		HeapProfile:   "heap.out",
+		AllocsProfile: "allocs.out",
+		BlockProfile:  "block.out",
+		MutexProfile:  "mutex.out",
+		Trace:         "trace.out",
+		PprofAddr:     "localhost:6060",
+	}
+	expected.Log = log.Config{Format: log.FormatJSON, Level: "debug"}
+	expected.Tracing = tracing.Config{Endpoint: "http://localhost:4318"}
+	expected.Metrics = metrics.Config{Addr: ":9090", File: "/var/lib/node-exporter/slicer.prom", Interval: time.Minute}
+	expected.Deduplication = config.DeduplicationLast
+	expected.DiskSpaceCheck = config.DiskSpaceCheckFail
+	expected.ExpectedInputSize = 2 * datasize.GB
 	expected.LogInterval = config.LogIntervalConfig{
 		Multiplier: 2,
 		Initial:    30 * time.Second,
@@ -99,6 +152,8 @@ func TestParseConfig_Full(t *testing.T) {
 	expected.AheadSlices = 1
 	expected.AheadBlocks = 16
 	expected.AheadBlockSize = datasize.MB
+	expected.InferTypes = true
+	expected.InputFormat = config.FormatJSONL
 	expected.InputSizeThreshold = 10 * datasize.MB
 	expected.Gzip = false
 	expected.GzipBlockSize = 2 * datasize.MB
@@ -108,12 +163,133 @@ func TestParseConfig_Full(t *testing.T) {
 	expected.MinBytesPerSlice = 3 * datasize.MB
 	expected.Mode = config.ModeRows
 	expected.NumberOfSlices = 456
+	expected.OutputFormat = config.FormatParquet
+	expected.OutputTypes = config.TypesInferred
+	expected.ParquetCompression = config.CompressionZstd
 	expected.RowsPerSlice = 789
+	expected.Sample = config.SampleConfig{Rows: 100, Seed: 42}
+	expected.SortBy = []string{"id", "name"}
+	expected.SpillBufferSize = 16 * datasize.MB
+	expected.TempDir = "/tmp/slicer"
+	expected.ZoneMapColumns = []string{"date", "price"}
+	expected.Explicit = config.PoolSettings{
+		GzipConcurrency: true,
+		GzipBlockSize:   true,
+		BufferSize:      true,
+		SpillBufferSize: true,
+	}
 
 	expected.Name = "my-table"
 	expected.InPath = "in/tables/my.csv"
 	expected.OutPath = "out/tables/my.csv"
 	expected.OutManifestPath = "out/tables/my.csv.manifest"
+	expected.OutPartsMetadataPath = "out/tables/my.csv.parts.json"
+	expected.OutProfilePath = "out/tables/my.csv.profile.json"
 
 	assert.Equal(t, expected, cfg)
 }
+
+func TestParseConfig_InvalidLog(t *testing.T) {
+	t.Parallel()
+
+	_, err := Parse([]string{
+		"--table-name", "my-table",
+		"--table-input-path", "in/tables/my.csv",
+		"--table-output-path", "out/tables/my.csv",
+		"--table-output-manifest-path", "out/tables/my.csv.manifest",
+		"--log-format", "xml",
+		"--log-level", "trace",
+	})
+	if assert.Error(t, err) {
+		assert.Equal(t, strings.TrimSpace(`
+configuration is not valid:
+- log-format must be one of [console json]
+- log-level must be one of [debug info warn error]
+`), err.Error())
+	}
+}
+
+func TestParseConfig_ConfigFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+table-name: my-table
+table-input-path: in/tables/my.csv
+table-output-path: out/tables/my.csv
+table-output-manifest-path: out/tables/my.csv.manifest
+gzip-level: 3
+sort-by: [id, name]
+`), 0o600))
+
+	// Flags take precedence over the config file
+	cfg, err := Parse([]string{"--config", path, "--gzip-level", "7"})
+	assert.NoError(t, err)
+
+	expected := Default()
+	expected.Name = "my-table"
+	expected.InPath = "in/tables/my.csv"
+	expected.OutPath = "out/tables/my.csv"
+	expected.OutManifestPath = "out/tables/my.csv.manifest"
+	expected.GzipLevel = 7
+	expected.SortBy = []string{"id", "name"}
+	expected.ZoneMapColumns = []string{} // empty flag value
+	assert.Equal(t, expected, cfg)
+}
+
+func TestParseConfig_ConfigFile_UnexpectedKey(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("table-nam: my-table\n"), 0o600))
+
+	_, err := Parse([]string{"--config", path})
+	if assert.Error(t, err) {
+		assert.Equal(t, fmt.Sprintf(`unexpected key "table-nam" in the config file "%s"`, path), err.Error())
+	}
+}
+
+func TestConfig_Settings(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := Parse([]string{
+		"--table-name", "my-table",
+		"--table-input-path", "in/tables/my.csv",
+		"--table-output-path", "out/tables/my.csv",
+		"--table-output-manifest-path", "out/tables/my.csv.manifest",
+		"--bytes-per-slice", "100MB",
+		"--log-interval-initial", "5s",
+		"--sample-fraction", "0.5",
+		"--zone-map-columns", "id",
+	})
+	require.NoError(t, err)
+
+	settings := cfg.Settings()
+	assert.Equal(t, "my-table", settings["table-name"])
+	assert.Equal(t, "100MB", settings["bytes-per-slice"])
+	assert.Equal(t, "5s", settings["log-interval-initial"])
+	assert.Equal(t, 0.5, settings["sample-fraction"])
+	assert.Equal(t, []string{"id"}, settings["zone-map-columns"])
+	assert.NotContains(t, settings, "config")
+	assert.NotContains(t, settings, "dump-config")
+
+	// Settings can be used as the config file
+	content, err := json.Marshal(settings)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, content, 0o600))
+	fromFile, err := Parse([]string{"--config", path})
+	assert.NoError(t, err)
+
+	// All keys are set by the config file, so the buffer pool settings are explicit
+	assert.Equal(t, config.PoolSettings{}, cfg.Explicit)
+	cfg.Explicit = config.PoolSettings{
+		GzipConcurrency: true,
+		GzipBlockSize:   true,
+		BufferSize:      true,
+		AheadBlocks:     true,
+		AheadBlockSize:  true,
+		SpillBufferSize: true,
+	}
+	assert.Equal(t, cfg, fromFile)
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/spf13/pflag"
+
+	"github.com/keboola/processor-split-table/internal/pkg/log"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer"
+	slicerConfig "github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+)
+
+const (
+	inspectUsageText = `Usage of "slicer inspect".
+
+  Prints slices, sizes, compression, dialect and columns of the input table as JSON.
+  Only the first row of a CSV table is read.
+`
+	verifyUsageText = `Usage of "slicer verify".
+
+  Reads and validates all rows of the input table without any output.
+  Each row must be a valid CSV row with the same number of columns as the header.
+  The command fails on the first invalid row.
+`
+	inspectInputUsageText = `
+
+  Input table:
+    --table-name
+        Table name for logging purposes, the base name of the input path by default.
+    --table-input-path
+        Path to the input table, either a file or a directory with slices.
+    --table-input-manifest-path
+        Path to the manifest of the input table.
+        It is used to get "delimiter", "enclosure" and "columns" fields, if any.
+
+
+  Environment variables:
+    Each flag can be specified via an env variable with the "SLICER_" prefix.
+    For example --table-input-path flag can be specified via SLICER_TABLE_INPUT_PATH env.
+
+
+  All flags:
+`
+)
+
+// InspectConfig is configuration of the "inspect" and "verify" commands.
+type InspectConfig struct {
+	slicer.InspectedTable `json:"table" mapstructure:",squash"`
+	Help                  bool              `json:"help" mapstructure:"help"`
+	MemoryLimit           datasize.ByteSize `json:"memoryLimit" mapstructure:"memory-limit"` // 0 = auto, see cgroup.Apply
+	CgroupLimits          bool              `json:"cgroupLimits" mapstructure:"cgroup-limits"`
+	Log                   log.Config        `json:"log" mapstructure:",squash"`
+}
+
+func DefaultInspect() InspectConfig {
+	cfg := InspectConfig{}
+	cfg.Config = slicerConfig.Default()
+	cfg.CgroupLimits = true
+	cfg.Log = log.DefaultConfig()
+	return cfg
+}
+
+// ParseInspect parses flags of the "inspect" or the "verify" command.
+func ParseInspect(command string, args []string) (InspectConfig, error) {
+	cfg := DefaultInspect()
+	_, err := parse(inspectFlags(command), args, &cfg)
+	return cfg, err
+}
+
+func InspectUsage() string {
+	return inspectUsage("inspect", inspectUsageText)
+}
+
+func VerifyUsage() string {
+	return inspectUsage("verify", verifyUsageText)
+}
+
+func inspectUsage(command, text string) string {
+	var b strings.Builder
+	b.WriteString(text)
+	b.WriteString(inspectInputUsageText)
+	b.WriteString(inspectFlags(command).FlagUsages())
+	b.WriteString("\n")
+	return b.String()
+}
+
+func inspectFlags(command string) *pflag.FlagSet {
+	cfg := DefaultInspect()
+
+	f := pflag.NewFlagSet("slicer "+command, pflag.ContinueOnError)
+	f.Bool("help", false, "Print help.")
+	addConfigFlag(f)
+	f.String("memory-limit", cfg.MemoryLimit.String(), "Soft memory limit, GOMEMLIMIT, "+memoryLimitAuto)
+	addCgroupFlag(f, cfg.CgroupLimits)
+	addLogFlags(f, cfg.Log)
+
+	f.String("table-name", cfg.Name, "Table name for logging purposes, the base name of the input path by default.")
+	f.String("table-input-path", cfg.InPath, "Path to the input table, either a file or a directory with slices.")
+	f.String("table-input-manifest-path", cfg.InManifestPath, "Path to the manifest describing the input table, if any.")
+
+	f.Float64("log-interval-multiplier", cfg.LogInterval.Multiplier, `Log interval multiplier.`)
+	f.Duration("log-interval-initial", cfg.LogInterval.Initial, `Initial log interval.`)
+	f.Duration("log-interval-maximum", cfg.LogInterval.Maximum, `Maximum log interval.`)
+
+	f.Uint32("ahead-slices", cfg.AheadSlices, "Number of input slices opened ahead.")
+	f.Uint32("ahead-blocks", cfg.AheadBlocks, "Number of blocks read ahead from an input slice, 0 disables read-ahead.")
+	f.String("ahead-block-size", cfg.AheadBlockSize.String(), "Size of a one read ahead input block.")
+
+	f.String("input-format", cfg.InputFormat.String(), `Format of the input table, "csv", "parquet", "jsonl" or "auto" detected by the extension.`)
+
+	return f
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+)
+
+func TestInspectUsage(t *testing.T) {
+	t.Parallel()
+	assert.NotEmpty(t, InspectUsage()) // asserted in the "cli/inspect-help" E2E test
+	assert.NotEmpty(t, VerifyUsage())
+}
+
+func TestParseInspect_Help(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := ParseInspect("verify", []string{"--help"})
+	require.Error(t, err)
+	assert.True(t, cfg.Help)
+}
+
+func TestParseInspect_Empty(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseInspect("inspect", []string{})
+	if assert.Error(t, err) {
+		assert.Equal(t, strings.TrimSpace(`
+configuration is not valid:
+- table-input-path is a required flag
+`), err.Error())
+	}
+}
+
+func TestParseInspect_Full(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := ParseInspect("inspect", []string{
+		"--table-name", "my-table",
+		"--table-input-path", "in/tables/my.csv",
+		"--table-input-manifest-path", "in/tables/my.csv.manifest",
+		"--input-format", "jsonl",
+		"--ahead-slices", "3",
+	})
+	assert.NoError(t, err)
+
+	expected := DefaultInspect()
+	expected.Name = "my-table"
+	expected.InPath = "in/tables/my.csv"
+	expected.InManifestPath = "in/tables/my.csv.manifest"
+	expected.InputFormat = config.FormatJSONL
+	expected.AheadSlices = 3
+	assert.Equal(t, expected, cfg)
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/spf13/pflag"
+
+	"github.com/keboola/processor-split-table/internal/pkg/log"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer"
+	slicerConfig "github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+)
+
+const mergeUsageText = `Usage of "slicer merge".
+
+  Merges slices of the input table to one CSV file.
+
+
+  Input and output table:
+    --table-name
+        Table name for logging purposes.
+    --table-input-path
+        Path to the input table, either a file or a directory with slices.
+    --table-input-manifest-path
+        Path to the manifest of the input table.
+        It is used to get "delimiter", "enclosure" and "columns" fields, if any.
+    --table-output-path
+        Path to the output CSV file, the parent directory must exist.
+    --header
+        Write the manifest columns as the first row.
+
+
+  Environment variables:
+    Each flag can be specified via an env variable with the "SLICER_" prefix.
+    For example --table-input-path flag can be specified via SLICER_TABLE_INPUT_PATH env.
+
+
+  All flags:
+`
+
+// MergeConfig is configuration of the "merge" command.
+type MergeConfig struct {
+	slicer.MergedTable `json:"table" mapstructure:",squash"`
+	Help               bool              `json:"help" mapstructure:"help"`
+	MemoryLimit        datasize.ByteSize `json:"memoryLimit" mapstructure:"memory-limit"` // 0 = auto, see cgroup.Apply
+	CgroupLimits       bool              `json:"cgroupLimits" mapstructure:"cgroup-limits"`
+	Log                log.Config        `json:"log" mapstructure:",squash"`
+}
+
+func DefaultMerge() MergeConfig {
+	cfg := MergeConfig{}
+	cfg.Config = slicerConfig.Default()
+	cfg.Gzip = false
+	cfg.CgroupLimits = true
+	cfg.Log = log.DefaultConfig()
+	return cfg
+}
+
+func ParseMerge(args []string) (MergeConfig, error) {
+	cfg := DefaultMerge()
+	_, err := parse(mergeFlags(), args, &cfg)
+	return cfg, err
+}
+
+func MergeUsage() string {
+	var b strings.Builder
+	b.WriteString(mergeUsageText)
+	b.WriteString(mergeFlags().FlagUsages())
+	b.WriteString("\n")
+	return b.String()
+}
+
+func mergeFlags() *pflag.FlagSet {
+	cfg := DefaultMerge()
+
+	f := pflag.NewFlagSet("slicer merge", pflag.ContinueOnError)
+	f.Bool("help", false, "Print help.")
+	addConfigFlag(f)
+	f.String("memory-limit", cfg.MemoryLimit.String(), "Soft memory limit, GOMEMLIMIT, "+memoryLimitAuto)
+	addCgroupFlag(f, cfg.CgroupLimits)
+	addLogFlags(f, cfg.Log)
+
+	f.String("table-name", cfg.Name, "Table name for logging purposes.")
+	f.String("table-input-path", cfg.InPath, "Path to the input table, either a file or a directory with slices.")
+	f.String("table-input-manifest-path", cfg.InManifestPath, "Path to the manifest describing the input table, if any.")
+	f.String("table-output-path", cfg.OutPath, "Path to the output CSV file.")
+	f.Bool("header", cfg.Header, "Write the manifest columns as the first row.")
+
+	f.Float64("log-interval-multiplier", cfg.LogInterval.Multiplier, `Log interval multiplier.`)
+	f.Duration("log-interval-initial", cfg.LogInterval.Initial, `Initial log interval.`)
+	f.Duration("log-interval-maximum", cfg.LogInterval.Maximum, `Maximum log interval.`)
+
+	f.Uint32("ahead-slices", cfg.AheadSlices, "Number of input slices opened ahead.")
+	f.Uint32("ahead-blocks", cfg.AheadBlocks, "Number of blocks read ahead from an input slice, 0 disables read-ahead.")
+	f.String("ahead-block-size", cfg.AheadBlockSize.String(), "Size of a one read ahead input block.")
+
+	f.String("input-format", cfg.InputFormat.String(), `Format of the input table, "csv", "parquet", "jsonl" or "auto" detected by the extension.`)
+
+	f.Bool("gzip", cfg.Gzip, "Enable gzip compression of the output file.")
+	f.Int("gzip-level", cfg.GzipLevel, "GZIP compression level, range: 1 best speed - 9 best compression.")
+	f.Uint32("gzip-concurrency", cfg.GzipConcurrency, "Number of parallel processed gzip blocks, 0 means the number of CPU threads.")
+	f.String("gzip-block-size", cfg.GzipBlockSize.String(), "Size of the one gzip block; allocated memory = concurrency * block size.")
+	f.String("buffer-size", cfg.BufferSize.String(), "Output buffer size when gzip compression is disabled.")
+
+	return f
+}
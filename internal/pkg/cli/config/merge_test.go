@@ -0,0 +1,64 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeUsage(t *testing.T) {
+	t.Parallel()
+	assert.NotEmpty(t, MergeUsage()) // asserted in the "cli/merge-help" E2E test
+}
+
+func TestParseMerge_Help(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := ParseMerge([]string{"--help"})
+	require.Error(t, err)
+	assert.True(t, cfg.Help)
+}
+
+func TestParseMerge_Empty(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseMerge([]string{})
+	if assert.Error(t, err) {
+		assert.Equal(t, strings.TrimSpace(`
+configuration is not valid:
+- table-name is a required flag
+- table-input-path is a required flag
+- table-output-path is a required flag
+`), err.Error())
+	}
+}
+
+func TestParseMerge_Full(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := ParseMerge([]string{
+		"--table-name", "my-table",
+		"--table-input-path", "in/tables/my.csv",
+		"--table-input-manifest-path", "in/tables/my.csv.manifest",
+		"--table-output-path", "out/my.csv.gz",
+		"--header",
+		"--gzip",
+		"--gzip-level", "4",
+		"--memory-limit", "128MB",
+	})
+	assert.NoError(t, err)
+
+	expected := DefaultMerge()
+	expected.Name = "my-table"
+	expected.InPath = "in/tables/my.csv"
+	expected.InManifestPath = "in/tables/my.csv.manifest"
+	expected.OutPath = "out/my.csv.gz"
+	expected.Header = true
+	expected.Gzip = true
+	expected.GzipLevel = 4
+	expected.MemoryLimit = 128 * datasize.MB
+	assert.Equal(t, expected, cfg)
+}
@@ -0,0 +1,33 @@
+// Package diskspace provides free space of a filesystem.
+package diskspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/c2h5oh/datasize"
+)
+
+// Free returns the space available to an unprivileged user on the filesystem of the path.
+// The path may not exist yet, then the nearest existing parent directory is used.
+// False is returned, if the free space cannot be determined on the platform.
+func Free(path string) (datasize.ByteSize, bool, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return 0, false, err
+	}
+
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return free(path)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return 0, false, err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return 0, false, nil
+		}
+		path = parent
+	}
+}
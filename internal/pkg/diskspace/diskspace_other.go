@@ -0,0 +1,12 @@
+//go:build !unix
+
+package diskspace
+
+import (
+	"github.com/c2h5oh/datasize"
+)
+
+// free is not implemented on the platform, the check is skipped.
+func free(_ string) (datasize.ByteSize, bool, error) {
+	return 0, false, nil
+}
@@ -0,0 +1,30 @@
+package diskspace
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFree(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("free space is not supported on Windows")
+	}
+
+	dir := t.TempDir()
+	free, ok, err := Free(dir)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Positive(t, free.Bytes())
+
+	// The nearest existing parent directory is used
+	freeMissing, ok, err := Free(filepath.Join(dir, "missing", "dir"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Positive(t, freeMissing.Bytes())
+}
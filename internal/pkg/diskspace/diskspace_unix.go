@@ -0,0 +1,18 @@
+//go:build unix
+
+package diskspace
+
+import (
+	"fmt"
+
+	"github.com/c2h5oh/datasize"
+	"golang.org/x/sys/unix"
+)
+
+func free(path string) (datasize.ByteSize, bool, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, false, fmt.Errorf(`cannot get free space of "%s": %w`, path, err)
+	}
+	return datasize.ByteSize(uint64(stat.Bavail) * uint64(stat.Bsize)), true, nil // nolint: unconvert
+}
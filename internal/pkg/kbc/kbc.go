@@ -11,6 +11,8 @@ import (
 const (
 	NewFilePermissions = 0o600
 	GzipFileExtension  = ".gz"
+	// StdStreamPath is used instead of a path to read from STDIN or to write to STDOUT.
+	StdStreamPath = "-"
 )
 
 type Error interface {
@@ -35,6 +37,24 @@ func UserErrorf(format string, a ...interface{}) error {
 	return &UserError{error: fmt.Errorf(format, a...)}
 }
 
+// ExitCodeError is an expected error with a custom exit code.
+// The reason has already been logged, so the message is not displayed by the CLI.
+type ExitCodeError struct {
+	error
+	code int
+}
+
+// ExitCode is processed in main.go.
+func (e ExitCodeError) ExitCode() int {
+	return e.code
+}
+
+// ExitCodeErrorf stops program execution with the exit code.
+func ExitCodeErrorf(code int, format string, a ...interface{}) error {
+	format = strings.TrimSpace(format)
+	return &ExitCodeError{error: fmt.Errorf(format, a...), code: code}
+}
+
 func GetDataDir() string {
 	return strings.TrimRight(getEnv("KBC_DATADIR", "/data"), "/")
 }
@@ -79,6 +79,20 @@ func (v Slices) MaxSliceSize() (maxSize datasize.ByteSize, err error) {
 	return maxSize, nil
 }
 
+// HasUnknownSize returns true, if a slice is not a regular file, for example a named pipe.
+func (v Slices) HasUnknownSize() (bool, error) {
+	for _, item := range v {
+		info, err := item.Info()
+		if err != nil {
+			return false, err
+		}
+		if !info.Mode().IsRegular() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (v Slice) Path() string {
 	return v.path
 }
@@ -3,12 +3,18 @@
 package log
 
 import (
+	"io"
 	"os"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+const (
+	FormatConsole = "console" // only messages, intended for humans, structured fields are omitted
+	FormatJSON    = "json"    // one JSON object per line, with the level, the timestamp and the structured fields
+)
+
 type Logger interface {
 	Debug(args ...any)
 	Info(args ...any)
@@ -18,18 +24,101 @@ type Logger interface {
 	Infof(template string, args ...any)
 	Warnf(template string, args ...any)
 	Errorf(template string, args ...any)
+	// Debugw, Infow, Warnw and Errorw log the message with additional structured fields, as key-value pairs.
+	Debugw(msg string, keysAndValues ...any)
+	Infow(msg string, keysAndValues ...any)
+	Warnw(msg string, keysAndValues ...any)
+	Errorw(msg string, keysAndValues ...any)
+}
+
+// Config of the logger.
+type Config struct {
+	Format string `json:"logFormat" mapstructure:"log-format" validate:"oneof=console json"`
+	Level  string `json:"logLevel" mapstructure:"log-level" validate:"oneof=debug info warn error"`
+}
+
+func DefaultConfig() Config {
+	return Config{Format: FormatConsole, Level: zapcore.InfoLevel.String()}
 }
 
 // NewLogger creates a logger that logs messages by default to STDOUT and Warn/Error levels to STDERR.
 func NewLogger() Logger {
+	return New(DefaultConfig(), false)
+}
+
+// New creates a logger according to the configuration.
+// Messages are logged to STDOUT and Warn/Error levels to STDERR.
+// All messages are logged to STDERR, if the stderrOnly is true, it is used when STDOUT is reserved for data.
+func New(cfg Config, stderrOnly bool) Logger {
+	if stderrOnly {
+		return newLogger(cfg, os.Stderr, os.Stderr)
+	}
+	return newLogger(cfg, os.Stdout, os.Stderr)
+}
+
+// newLogger creates a logger that logs messages to the stdout writer and Warn/Error levels to the stderr writer.
+func newLogger(cfg Config, stdout, stderr io.Writer) Logger {
+	minLevel, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		minLevel = zapcore.InfoLevel
+	}
+
 	toInfoLevel := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
-		return l == zapcore.DebugLevel || l == zapcore.InfoLevel
+		return l >= minLevel && l <= zapcore.InfoLevel
+	})
+	fromWarnLevel := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return l >= minLevel && l >= zapcore.WarnLevel
 	})
-	fromWarnLevel := zapcore.WarnLevel
 
-	encoder := zapcore.NewConsoleEncoder(zapcore.EncoderConfig{MessageKey: "msg"})
+	newCore := func(out io.Writer, level zapcore.LevelEnabler) zapcore.Core {
+		if cfg.Format == FormatJSON {
+			return zapcore.NewCore(zapcore.NewJSONEncoder(jsonEncoderConfig()), zapcore.AddSync(out), level)
+		}
+		return consoleCore{Core: zapcore.NewCore(zapcore.NewConsoleEncoder(zapcore.EncoderConfig{MessageKey: "msg"}), zapcore.AddSync(out), level)}
+	}
+
 	return zap.New(zapcore.NewTee(
-		zapcore.NewCore(encoder, zapcore.AddSync(os.Stdout), toInfoLevel),
-		zapcore.NewCore(encoder, zapcore.AddSync(os.Stderr), fromWarnLevel),
+		newCore(stdout, toInfoLevel),
+		newCore(stderr, fromWarnLevel),
 	)).Sugar()
 }
+
+// With returns a logger with the structured fields, as key-value pairs, added to each message.
+func With(logger Logger, keysAndValues ...any) Logger {
+	if l, ok := logger.(*zap.SugaredLogger); ok {
+		return l.With(keysAndValues...)
+	}
+	return logger
+}
+
+func jsonEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		TimeKey:        "time",
+		LevelKey:       "level",
+		MessageKey:     "msg",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+	}
+}
+
+// consoleCore omits structured fields, the console output contains only messages, the values are part of them.
+type consoleCore struct {
+	zapcore.Core
+}
+
+func (c consoleCore) With([]zapcore.Field) zapcore.Core {
+	return c
+}
+
+func (c consoleCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c consoleCore) Write(entry zapcore.Entry, _ []zapcore.Field) error {
+	return c.Core.Write(entry, nil)
+}
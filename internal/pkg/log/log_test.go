@@ -0,0 +1,53 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_Console(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	logger := With(newLogger(DefaultConfig(), &stdout, &stderr), "table", "my-table")
+	logger.Debug("debug message")
+	logger.Infow("info message", "rows", 123)
+	logger.Warnf("warn %s", "message")
+	logger.Error("error message")
+
+	// Fields are omitted, debug level is disabled by default
+	assert.Equal(t, "info message\n", stdout.String())
+	assert.Equal(t, "warn message\nerror message\n", stderr.String())
+}
+
+func TestLogger_JSON(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	logger := With(newLogger(Config{Format: FormatJSON, Level: "debug"}, &stdout, &stderr), "table", "my-table")
+	logger.Debug("debug message")
+	logger.Infow("info message", "rows", 123)
+	logger.Warn("warn message")
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Regexp(t, `^{"level":"debug","time":"[^"]+","msg":"debug message","table":"my-table"}$`, lines[0])
+		assert.Regexp(t, `^{"level":"info","time":"[^"]+","msg":"info message","table":"my-table","rows":123}$`, lines[1])
+	}
+	assert.Regexp(t, `^{"level":"warn","time":"[^"]+","msg":"warn message","table":"my-table"}\n$`, stderr.String())
+}
+
+func TestLogger_Level(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	logger := newLogger(Config{Format: FormatConsole, Level: "warn"}, &stdout, &stderr)
+	logger.Info("info message")
+	logger.Warn("warn message")
+
+	assert.Empty(t, stdout.String())
+	assert.Equal(t, "warn message\n", stderr.String())
+}
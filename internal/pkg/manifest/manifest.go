@@ -17,9 +17,17 @@ const (
 	EnclosureDelimiter = '"'
 )
 
+// ColumnMetadata is an item of the manifest "column_metadata" key.
+type ColumnMetadata struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
 // Manifest is a parsed table manifest.
 // The original content is always preserved, so it is represented as an OrderedMap.
-// There is only one setter SetColumns, it is used to move the list of columns from CSV header to the manifest.
+// There are only two setters:
+//   - SetColumns is used to move the list of columns from CSV header to the manifest.
+//   - SetColumnMetadata is used to store the inferred column types.
 type Manifest struct {
 	path     string
 	exists   bool
@@ -113,12 +121,81 @@ func (m *Manifest) Columns() []string {
 	return m.columns
 }
 
+// PrimaryKey returns the manifest "primary_key", a null is the same as an empty key.
+// The key is validated only when it is used, so a manifest with an unexpected value can be sliced without the deduplication.
+func (m *Manifest) PrimaryKey() ([]string, error) {
+	val, ok := m.content.Get("primary_key")
+	if !ok || val == nil {
+		return nil, nil
+	}
+	raw, ok := val.([]interface{})
+	if !ok {
+		return nil, kbc.UserErrorf("unexpected type \"%T\" of the manifest \"primary_key\" key", val)
+	}
+	return toStrings(raw), nil
+}
+
 func (m *Manifest) SetColumns(columns []string) {
 	m.content.Set("columns", columns)
 	m.columns = columns
 	m.modified = true
 }
 
+// SetColumnMetadata sets the "column_metadata" items of the column.
+// Existing items with the same keys are replaced, other items are preserved.
+func (m *Manifest) SetColumnMetadata(column string, items []ColumnMetadata) error {
+	// Get existing metadata of all columns
+	var allColumns *orderedmap.OrderedMap
+	val, _ := m.content.Get("column_metadata")
+	switch v := val.(type) {
+	case nil:
+		allColumns = orderedmap.New()
+	case orderedmap.OrderedMap:
+		allColumns = &v
+	case *orderedmap.OrderedMap:
+		allColumns = v
+	case []interface{}:
+		// An empty object can be encoded as an empty array
+		if len(v) > 0 {
+			return kbc.UserErrorf("unexpected type \"%T\" of the manifest \"column_metadata\" key", val)
+		}
+		allColumns = orderedmap.New()
+	default:
+		return kbc.UserErrorf("unexpected type \"%T\" of the manifest \"column_metadata\" key", val)
+	}
+
+	// Keep existing items with other keys
+	replaced := make(map[string]bool, len(items))
+	for _, item := range items {
+		replaced[item.Key] = true
+	}
+	var out []interface{}
+	if existing, found := allColumns.Get(column); found {
+		existingItems, ok := existing.([]interface{})
+		if !ok {
+			return kbc.UserErrorf("unexpected type \"%T\" of the manifest \"column_metadata.%s\" key", existing, column)
+		}
+		for _, item := range existingItems {
+			if itemMap, ok := item.(orderedmap.OrderedMap); ok {
+				if key, _ := itemMap.Get("key"); replaced[fmt.Sprintf("%v", key)] {
+					continue
+				}
+			}
+			out = append(out, item)
+		}
+	}
+
+	// Add new items
+	for _, item := range items {
+		out = append(out, item)
+	}
+
+	allColumns.Set(column, out)
+	m.content.Set("column_metadata", allColumns)
+	m.modified = true
+	return nil
+}
+
 func (m *Manifest) Delimiter() byte {
 	return m.delimiter
 }
@@ -150,11 +227,7 @@ func loadManifestContent(path string) (content *orderedmap.OrderedMap, found boo
 	// Convert columns []interface -> []string
 	if val, ok := content.Get("columns"); ok {
 		if raw, ok := val.([]interface{}); ok {
-			strings := make([]string, len(raw))
-			for i := range raw {
-				strings[i] = fmt.Sprintf("%v", raw[i])
-			}
-			content.Set("columns", strings)
+			content.Set("columns", toStrings(raw))
 		} else {
 			return nil, false, fmt.Errorf("unexpected type \"%T\" of the manifest \"columns\" key", val)
 		}
@@ -162,3 +235,11 @@ func loadManifestContent(path string) (content *orderedmap.OrderedMap, found boo
 
 	return content, true, nil
 }
+
+func toStrings(raw []interface{}) []string {
+	out := make([]string, len(raw))
+	for i := range raw {
+		out[i] = fmt.Sprintf("%v", raw[i])
+	}
+	return out
+}
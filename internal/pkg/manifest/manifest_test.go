@@ -62,6 +62,76 @@ func TestGetSet(t *testing.T) {
 	}
 }
 
+func TestPrimaryKey(t *testing.T) {
+	t.Parallel()
+
+	manifestPath := t.TempDir() + "/manifest.json"
+	require.NoError(t, os.WriteFile(manifestPath, []byte(`{"columns": ["id", "name"], "primary_key": ["id"]}`), kbc.NewFilePermissions))
+
+	manifest, err := LoadManifest(manifestPath)
+	require.NoError(t, err)
+	primaryKey, err := manifest.PrimaryKey()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id"}, primaryKey)
+
+	// Null is an empty key
+	require.NoError(t, os.WriteFile(manifestPath, []byte(`{"primary_key": null}`), kbc.NewFilePermissions))
+	manifest, err = LoadManifest(manifestPath)
+	require.NoError(t, err)
+	primaryKey, err = manifest.PrimaryKey()
+	require.NoError(t, err)
+	assert.Empty(t, primaryKey)
+
+	// Invalid type, the manifest can be loaded, the key is validated only when it is used
+	require.NoError(t, os.WriteFile(manifestPath, []byte(`{"primary_key": "id"}`), kbc.NewFilePermissions))
+	manifest, err = LoadManifest(manifestPath)
+	require.NoError(t, err)
+	_, err = manifest.PrimaryKey()
+	if assert.Error(t, err) {
+		assert.Equal(t, `unexpected type "string" of the manifest "primary_key" key`, err.Error())
+	}
+}
+
+func TestSetColumnMetadata(t *testing.T) {
+	t.Parallel()
+
+	manifestPath := t.TempDir() + "/manifest.json"
+	input := `{
+  "columns": ["id", "name"],
+  "column_metadata": {
+    "id": [{"key": "KBC.datatype.basetype", "value": "STRING"}, {"key": "KBC.description", "value": "ID"}]
+  }
+}`
+	require.NoError(t, os.WriteFile(manifestPath, []byte(input), kbc.NewFilePermissions))
+
+	manifest, err := LoadManifest(manifestPath)
+	require.NoError(t, err)
+	require.NoError(t, manifest.SetColumnMetadata("id", []ColumnMetadata{{Key: "KBC.datatype.basetype", Value: "INTEGER"}}))
+	require.NoError(t, manifest.SetColumnMetadata("name", []ColumnMetadata{{Key: "KBC.datatype.basetype", Value: "STRING"}}))
+	assert.True(t, manifest.Modified())
+	require.NoError(t, manifest.WriteTo(manifestPath))
+
+	// Existing items with other keys are preserved
+	content, err := os.ReadFile(manifestPath)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "columns": ["id", "name"],
+  "column_metadata": {
+    "id": [{"key": "KBC.description", "value": "ID"}, {"key": "KBC.datatype.basetype", "value": "INTEGER"}],
+    "name": [{"key": "KBC.datatype.basetype", "value": "STRING"}]
+  }
+}`, string(content))
+
+	// Invalid type
+	require.NoError(t, os.WriteFile(manifestPath, []byte(`{"column_metadata": "abc"}`), kbc.NewFilePermissions))
+	manifest, err = LoadManifest(manifestPath)
+	require.NoError(t, err)
+	err = manifest.SetColumnMetadata("id", nil)
+	if assert.Error(t, err) {
+		assert.Equal(t, `unexpected type "string" of the manifest "column_metadata" key`, err.Error())
+	}
+}
+
 func getTestData() []testData {
 	return []testData{
 		{
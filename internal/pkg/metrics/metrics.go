@@ -0,0 +1,211 @@
+// Package metrics provides Prometheus metrics of the slicing.
+// Metrics can be served by an HTTP listener and/or periodically written to a node-exporter textfile.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"runtime/debug"
+	runtimeMetrics "runtime/metrics"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/keboola/processor-split-table/internal/pkg/log"
+)
+
+const (
+	namespace         = "slicer"
+	tableLabel        = "table"
+	memoryTotalMetric = "/memory/classes/total:bytes"
+	memoryFreeMetric  = "/memory/classes/heap/released:bytes"
+)
+
+// Config of the metrics.
+type Config struct {
+	Addr     string        `json:"metricsAddr" mapstructure:"metrics-addr"`                           // optional, for example ":9090"
+	File     string        `json:"metricsFile" mapstructure:"metrics-file"`                           // optional, node-exporter textfile
+	Interval time.Duration `json:"metricsInterval" mapstructure:"metrics-interval" validate:"min=1s"` // textfile write interval
+}
+
+func DefaultConfig() Config {
+	return Config{Interval: 15 * time.Second}
+}
+
+// Enabled returns true, if the metrics are served or written to the file.
+func (c Config) Enabled() bool {
+	return c.Addr != "" || c.File != ""
+}
+
+// Metrics is a registry of the slicing metrics, a nil *Metrics is valid and disables metrics.
+type Metrics struct {
+	registry      *prometheus.Registry
+	bytesRead     *prometheus.CounterVec
+	rowsWritten   *prometheus.CounterVec
+	slicesCreated *prometheus.CounterVec
+	gzipSeconds   *prometheus.CounterVec
+}
+
+// Table contains metrics of the one table, a nil *Table is valid and does nothing.
+type Table struct {
+	bytesRead     prometheus.Counter
+	rowsWritten   prometheus.Counter
+	slicesCreated prometheus.Counter
+	gzipSeconds   prometheus.Counter
+}
+
+func New() *Metrics {
+	m := &Metrics{registry: prometheus.NewRegistry()}
+
+	newCounter := func(name, help string) *prometheus.CounterVec {
+		counter := prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: namespace, Name: name, Help: help}, []string{tableLabel})
+		m.registry.MustRegister(counter)
+		return counter
+	}
+	m.bytesRead = newCounter("read_bytes_total", "Bytes read from the input table, before decompression.")
+	m.rowsWritten = newCounter("written_rows_total", "Rows written to the output table.")
+	m.slicesCreated = newCounter("created_slices_total", "Slices of the output table created.")
+	m.gzipSeconds = newCounter("gzip_seconds_total", "Time spent by writing to the gzip writers, including waiting for the compression.")
+
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{Namespace: namespace, Name: "memory_limit_bytes", Help: "Soft memory limit, GOMEMLIMIT."},
+			func() float64 { return float64(debug.SetMemoryLimit(-1)) },
+		),
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{Namespace: namespace, Name: "memory_used_bytes", Help: "Memory used by the Go runtime, it is compared with the GOMEMLIMIT."},
+			memoryUsed,
+		),
+	)
+
+	return m
+}
+
+// Table returns metrics of the table.
+func (m *Metrics) Table(name string) *Table {
+	if m == nil {
+		return nil
+	}
+	return &Table{
+		bytesRead:     m.bytesRead.WithLabelValues(name),
+		rowsWritten:   m.rowsWritten.WithLabelValues(name),
+		slicesCreated: m.slicesCreated.WithLabelValues(name),
+		gzipSeconds:   m.gzipSeconds.WithLabelValues(name),
+	}
+}
+
+// Start serves and/or writes the metrics according to the configuration.
+// The returned stop function stops the HTTP listener and writes the textfile for the last time.
+func Start(logger log.Logger, cfg Config) (m *Metrics, stop func() error, err error) {
+	m = New()
+	var stops []func() error
+
+	// Serve metrics via HTTP, the listener is created synchronously, so an invalid address is reported
+	if cfg.Addr != "" {
+		listener, err := net.Listen("tcp", cfg.Addr)
+		if err != nil {
+			return nil, nil, err
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+		srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Warnf(`Metrics server failed: %s`, err)
+			}
+		}()
+		logger.Infof(`Metrics are served on "http://%s/metrics".`, listener.Addr())
+		stops = append(stops, func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return srv.Shutdown(ctx)
+		})
+	}
+
+	// Write metrics periodically to the textfile
+	if cfg.File != "" {
+		if err := m.WriteTextfile(cfg.File); err != nil {
+			return nil, nil, err
+		}
+		done := make(chan struct{})
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(cfg.Interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					if err := m.WriteTextfile(cfg.File); err != nil {
+						logger.Warnf(`Cannot write metrics: %s`, err)
+					}
+				}
+			}
+		}()
+		stops = append(stops, func() error {
+			close(done)
+			wg.Wait()
+			return m.WriteTextfile(cfg.File)
+		})
+	}
+
+	stop = func() error {
+		var errs []error
+		for _, fn := range stops {
+			errs = append(errs, fn())
+		}
+		return errors.Join(errs...)
+	}
+	return m, stop, nil
+}
+
+// WriteTextfile writes the metrics atomically to the file in the text format, it can be read by the node-exporter.
+func (m *Metrics) WriteTextfile(path string) error {
+	return prometheus.WriteToTextfile(path, m.registry)
+}
+
+// AddBytesRead adds the bytes read from the input table.
+func (t *Table) AddBytesRead(n int) {
+	if t != nil {
+		t.bytesRead.Add(float64(n))
+	}
+}
+
+// AddRow increments the rows written to the output table.
+func (t *Table) AddRow() {
+	if t != nil {
+		t.rowsWritten.Inc()
+	}
+}
+
+// AddSlice increments the slices created.
+func (t *Table) AddSlice() {
+	if t != nil {
+		t.slicesCreated.Inc()
+	}
+}
+
+// AddGzipDuration adds the time spent by writing to a gzip writer.
+func (t *Table) AddGzipDuration(d time.Duration) {
+	if t != nil {
+		t.gzipSeconds.Add(d.Seconds())
+	}
+}
+
+// memoryUsed returns the total memory mapped by the Go runtime minus the memory released to the OS,
+// it is the value that is compared with the soft memory limit.
+func memoryUsed() float64 {
+	samples := []runtimeMetrics.Sample{{Name: memoryTotalMetric}, {Name: memoryFreeMetric}}
+	runtimeMetrics.Read(samples)
+	if samples[0].Value.Kind() != runtimeMetrics.KindUint64 || samples[1].Value.Kind() != runtimeMetrics.KindUint64 {
+		return 0
+	}
+	return float64(samples[0].Value.Uint64() - samples[1].Value.Uint64())
+}
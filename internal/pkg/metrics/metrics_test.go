@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestTable_Nil(t *testing.T) {
+	t.Parallel()
+
+	var m *Metrics
+	table := m.Table("my-table")
+	assert.Nil(t, table)
+
+	// Methods of a nil table do nothing
+	assert.NotPanics(t, func() {
+		table.AddBytesRead(123)
+		table.AddRow()
+		table.AddSlice()
+		table.AddGzipDuration(time.Second)
+	})
+}
+
+func TestMetrics_WriteTextfile(t *testing.T) {
+	t.Parallel()
+
+	m := New()
+	table := m.Table("my-table")
+	table.AddBytesRead(100)
+	table.AddBytesRead(23)
+	table.AddRow()
+	table.AddRow()
+	table.AddSlice()
+	table.AddGzipDuration(1500 * time.Millisecond)
+
+	path := filepath.Join(t.TempDir(), "slicer.prom")
+	require.NoError(t, m.WriteTextfile(path))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(content), `slicer_read_bytes_total{table="my-table"} 123`+"\n")
+	assert.Contains(t, string(content), `slicer_written_rows_total{table="my-table"} 2`+"\n")
+	assert.Contains(t, string(content), `slicer_created_slices_total{table="my-table"} 1`+"\n")
+	assert.Contains(t, string(content), `slicer_gzip_seconds_total{table="my-table"} 1.5`+"\n")
+	assert.Regexp(t, `(?m)^slicer_memory_limit_bytes \S+$`, string(content))
+	assert.Regexp(t, `(?m)^slicer_memory_used_bytes \S+$`, string(content))
+}
+
+func TestStart(t *testing.T) {
+	t.Parallel()
+
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core).Sugar()
+	path := filepath.Join(t.TempDir(), "slicer.prom")
+
+	m, stop, err := Start(logger, Config{Addr: "127.0.0.1:0", File: path, Interval: time.Hour})
+	require.NoError(t, err)
+
+	// The textfile is written immediately
+	assert.FileExists(t, path)
+
+	// Get the metrics via HTTP
+	m.Table("my-table").AddRow()
+	require.Equal(t, 1, logs.Len())
+	addr := regexp.MustCompile(`"(http://[^"]+)"`).FindStringSubmatch(logs.All()[0].Message)
+	require.Len(t, addr, 2)
+	resp, err := http.Get(addr[1]) // nolint: noctx
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Contains(t, string(body), `slicer_written_rows_total{table="my-table"} 1`+"\n")
+
+	// The textfile is written on stop
+	require.NoError(t, stop())
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `slicer_written_rows_total{table="my-table"} 1`+"\n")
+}
+
+func TestStart_InvalidAddr(t *testing.T) {
+	t.Parallel()
+
+	_, _, err := Start(zap.NewNop().Sugar(), Config{Addr: "invalid", Interval: time.Second})
+	assert.Error(t, err)
+}
@@ -7,17 +7,63 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
 
 	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/profiling"
 	slicerConfig "github.com/keboola/processor-split-table/internal/pkg/slicer/config"
 )
 
 type Config struct {
 	Parameters slicerConfig.Config `json:"parameters" validate:"required"`
+	// Processor specific parameters, they are decoded from the same "parameters" key.
+	Processor Parameters `json:"-"`
+}
+
+// Parameters are not part of the slicerConfig.Config, because they are not used by the CLI.
+type Parameters struct {
+	// PartsMetadata enables writing of the slices metadata of each table to the "out/files" directory.
+	PartsMetadata bool `json:"partsMetadata"`
+	// Profile enables writing of the columns statistics of each table to the "out/files" directory.
+	Profile bool `json:"profile"`
+	// Result enables writing of the machine-readable result of each table to the "out/files" directory.
+	Result bool `json:"result"`
+	// Profiling enables writing of the Go runtime profiles to the "out/files/profiling" directory.
+	Profiling ProfilingParameters `json:"profiling"`
+}
+
+// ProfilingParameters enable the profiles, the processor is run without CLI flags, so the paths are fixed.
+type ProfilingParameters struct {
+	CPU       bool   `json:"cpu"`
+	Heap      bool   `json:"heap"`
+	Allocs    bool   `json:"allocs"`
+	Block     bool   `json:"block"`
+	Mutex     bool   `json:"mutex"`
+	Trace     bool   `json:"trace"`
+	PprofAddr string `json:"pprofAddr"`
+}
+
+// Config returns configuration of the enabled profiles, they are written to the dir.
+func (p ProfilingParameters) Config(dir string) profiling.Config {
+	path := func(enabled bool, file string) string {
+		if enabled {
+			return filepath.Join(dir, file)
+		}
+		return ""
+	}
+	return profiling.Config{
+		CPUProfile:    path(p.CPU, "cpu.pprof"),
+		HeapProfile:   path(p.Heap, "heap.pprof"),
+		AllocsProfile: path(p.Allocs, "allocs.pprof"),
+		BlockProfile:  path(p.Block, "block.pprof"),
+		MutexProfile:  path(p.Mutex, "mutex.pprof"),
+		Trace:         path(p.Trace, "trace.out"),
+		PprofAddr:     p.PprofAddr,
+	}
 }
 
 func LoadConfig(configPath string) (cfg *Config, err error) {
@@ -51,6 +97,15 @@ func LoadConfig(configPath string) (cfg *Config, err error) {
 		return nil, kbc.UserErrorf("invalid configuration: %s", processJSONError(err))
 	}
 
+	// Parse processor specific parameters
+	processorConf := struct {
+		Parameters *Parameters `json:"parameters"`
+	}{Parameters: &conf.Processor}
+	err = json.Unmarshal(content, &processorConf)
+	if err != nil {
+		return nil, kbc.UserErrorf("invalid configuration: %s", processJSONError(err))
+	}
+
 	// Validate
 	if err := validate(conf); err != nil {
 		return nil, kbc.UserErrorf("invalid configuration: %s", err)
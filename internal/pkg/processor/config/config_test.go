@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/keboola/processor-split-table/internal/pkg/profiling"
 	slicerConfig "github.com/keboola/processor-split-table/internal/pkg/slicer/config"
 )
 
@@ -81,6 +82,102 @@ func getTestData() []testData {
 			error:    `invalid configuration: key="parameters.gzipLevel", value="10" failed on the "max" validation`,
 			expected: nil,
 		},
+		{
+			comment:  "invalid output format",
+			input:    "{\"parameters\": {\"outputFormat\": \"xml\"}}",
+			error:    `invalid configuration: unexpected value "xml" for "format", use "csv", "parquet", "jsonl", "arrow" or "auto" for the input`,
+			expected: nil,
+		},
+		{
+			comment:  "invalid parquet compression",
+			input:    "{\"parameters\": {\"parquetCompression\": \"lz4\"}}",
+			error:    `invalid configuration: unexpected value "lz4" for "parquetCompression", use "none", "snappy" or "zstd"`,
+			expected: nil,
+		},
+		{
+			comment:  "max value sample fraction",
+			input:    "{\"parameters\": {\"sample\": {\"fraction\": 1.5}}}",
+			error:    `invalid configuration: key="parameters.sample.fraction", value="1.5" failed on the "max" validation`,
+			expected: nil,
+		},
+		{
+			comment: "sample",
+			input:   "{\"parameters\": {\"sample\": {\"rows\": 100, \"seed\": 42}}}",
+			error:   "",
+			expected: &Config{
+				Parameters: slicerConfig.Config{
+					Mode:             slicerConfig.ModeBytes,
+					BytesPerSlice:    500 * datasize.MB,
+					RowsPerSlice:     1_000_000,
+					NumberOfSlices:   60,
+					MinBytesPerSlice: 4 * datasize.MB,
+					LogInterval: slicerConfig.LogIntervalConfig{
+						Multiplier: 1.5,
+						Initial:    10 * time.Second,
+						Maximum:    15 * time.Minute,
+					},
+					AheadSlices:        1,
+					AheadBlocks:        16,
+					AheadBlockSize:     datasize.MB,
+					InputFormat:        slicerConfig.FormatAuto,
+					InputSizeThreshold: 50 * datasize.MB,
+					Gzip:               true,
+					GzipLevel:          1,
+					GzipConcurrency:    0,
+					GzipBlockSize:      1 * datasize.MB,
+					BufferSize:         20 * datasize.MB,
+					OutputTypes:        slicerConfig.TypesString,
+					ParquetCompression: slicerConfig.CompressionSnappy,
+					SpillBufferSize:    32 * datasize.MB,
+					DiskSpaceCheck:     slicerConfig.DiskSpaceCheckWarn,
+					Sample: slicerConfig.SampleConfig{
+						Rows: 100,
+						Seed: 42,
+					},
+				},
+			},
+		},
+		{
+			comment: "parts metadata",
+			input:   "{\"parameters\": {\"partsMetadata\": true}}",
+			error:   "",
+			expected: &Config{
+				Parameters: slicerConfig.Default(),
+				Processor:  Parameters{PartsMetadata: true},
+			},
+		},
+		{
+			comment: "profile",
+			input:   "{\"parameters\": {\"profile\": true}}",
+			error:   "",
+			expected: &Config{
+				Parameters: slicerConfig.Default(),
+				Processor:  Parameters{Profile: true},
+			},
+		},
+		{
+			comment: "result",
+			input:   "{\"parameters\": {\"result\": true}}",
+			error:   "",
+			expected: &Config{
+				Parameters: slicerConfig.Default(),
+				Processor:  Parameters{Result: true},
+			},
+		},
+		{
+			comment: "profiling",
+			input:   "{\"parameters\": {\"profiling\": {\"cpu\": true, \"heap\": true, \"trace\": true, \"pprofAddr\": \"localhost:6060\"}}}",
+			error:   "",
+			expected: &Config{
+				Parameters: slicerConfig.Default(),
+				Processor: Parameters{Profiling: ProfilingParameters{
+					CPU:       true,
+					Heap:      true,
+					Trace:     true,
+					PprofAddr: "localhost:6060",
+				}},
+			},
+		},
 		{
 			comment:  "default values 1",
 			input:    "{}",
@@ -112,12 +209,17 @@ func getTestData() []testData {
 					AheadSlices:        1,
 					AheadBlocks:        16,
 					AheadBlockSize:     datasize.MB,
+					InputFormat:        slicerConfig.FormatAuto,
 					InputSizeThreshold: 50 * datasize.MB,
 					Gzip:               true,
 					GzipLevel:          5,
 					GzipConcurrency:    0,
 					GzipBlockSize:      1 * datasize.MB,
 					BufferSize:         20 * datasize.MB,
+					OutputTypes:        slicerConfig.TypesString,
+					ParquetCompression: slicerConfig.CompressionSnappy,
+					SpillBufferSize:    32 * datasize.MB,
+					DiskSpaceCheck:     slicerConfig.DiskSpaceCheckWarn,
 				},
 			},
 		},
@@ -140,12 +242,18 @@ func getTestData() []testData {
 					AheadSlices:        1,
 					AheadBlocks:        16,
 					AheadBlockSize:     datasize.MB,
+					InputFormat:        slicerConfig.FormatAuto,
 					InputSizeThreshold: 50 * datasize.MB,
 					Gzip:               true,
 					GzipLevel:          1,
 					GzipConcurrency:    8,
 					GzipBlockSize:      1 * datasize.MB,
 					BufferSize:         3 * datasize.MB,
+					OutputTypes:        slicerConfig.TypesString,
+					ParquetCompression: slicerConfig.CompressionSnappy,
+					SpillBufferSize:    32 * datasize.MB,
+					DiskSpaceCheck:     slicerConfig.DiskSpaceCheckWarn,
+					Explicit:           slicerConfig.PoolSettings{GzipConcurrency: true, GzipBlockSize: true, BufferSize: true},
 				},
 			},
 		},
@@ -168,12 +276,17 @@ func getTestData() []testData {
 					AheadSlices:        1,
 					AheadBlocks:        16,
 					AheadBlockSize:     datasize.MB,
+					InputFormat:        slicerConfig.FormatAuto,
 					InputSizeThreshold: 50 * datasize.MB,
 					Gzip:               false,
 					GzipLevel:          1,
 					GzipConcurrency:    0,
 					GzipBlockSize:      1 * datasize.MB,
 					BufferSize:         20 * datasize.MB,
+					OutputTypes:        slicerConfig.TypesString,
+					ParquetCompression: slicerConfig.CompressionSnappy,
+					SpillBufferSize:    32 * datasize.MB,
+					DiskSpaceCheck:     slicerConfig.DiskSpaceCheckWarn,
 				},
 			},
 		},
@@ -196,12 +309,17 @@ func getTestData() []testData {
 					AheadSlices:        1,
 					AheadBlocks:        16,
 					AheadBlockSize:     datasize.MB,
+					InputFormat:        slicerConfig.FormatAuto,
 					InputSizeThreshold: 50 * datasize.MB,
 					Gzip:               true,
 					GzipLevel:          1,
 					GzipConcurrency:    0,
 					GzipBlockSize:      1 * datasize.MB,
 					BufferSize:         20 * datasize.MB,
+					OutputTypes:        slicerConfig.TypesString,
+					ParquetCompression: slicerConfig.CompressionSnappy,
+					SpillBufferSize:    32 * datasize.MB,
+					DiskSpaceCheck:     slicerConfig.DiskSpaceCheckWarn,
 				},
 			},
 		},
@@ -224,12 +342,17 @@ func getTestData() []testData {
 					AheadSlices:        1,
 					AheadBlocks:        16,
 					AheadBlockSize:     datasize.MB,
+					InputFormat:        slicerConfig.FormatAuto,
 					InputSizeThreshold: 50 * datasize.MB,
 					Gzip:               true,
 					GzipLevel:          1,
 					GzipConcurrency:    0,
 					GzipBlockSize:      1 * datasize.MB,
 					BufferSize:         20 * datasize.MB,
+					OutputTypes:        slicerConfig.TypesString,
+					ParquetCompression: slicerConfig.CompressionSnappy,
+					SpillBufferSize:    32 * datasize.MB,
+					DiskSpaceCheck:     slicerConfig.DiskSpaceCheckWarn,
 				},
 			},
 		},
@@ -252,12 +375,17 @@ func getTestData() []testData {
 					AheadSlices:        1,
 					AheadBlocks:        16,
 					AheadBlockSize:     datasize.MB,
+					InputFormat:        slicerConfig.FormatAuto,
 					InputSizeThreshold: 50 * datasize.MB,
 					Gzip:               true,
 					GzipLevel:          1,
 					GzipConcurrency:    0,
 					GzipBlockSize:      1 * datasize.MB,
 					BufferSize:         20 * datasize.MB,
+					OutputTypes:        slicerConfig.TypesString,
+					ParquetCompression: slicerConfig.CompressionSnappy,
+					SpillBufferSize:    32 * datasize.MB,
+					DiskSpaceCheck:     slicerConfig.DiskSpaceCheckWarn,
 				},
 			},
 		},
@@ -280,12 +408,17 @@ func getTestData() []testData {
 					AheadSlices:        1,
 					AheadBlocks:        16,
 					AheadBlockSize:     datasize.MB,
+					InputFormat:        slicerConfig.FormatAuto,
 					InputSizeThreshold: 50 * datasize.MB,
 					Gzip:               true,
 					GzipLevel:          1,
 					GzipConcurrency:    0,
 					GzipBlockSize:      1 * datasize.MB,
 					BufferSize:         20 * datasize.MB,
+					OutputTypes:        slicerConfig.TypesString,
+					ParquetCompression: slicerConfig.CompressionSnappy,
+					SpillBufferSize:    32 * datasize.MB,
+					DiskSpaceCheck:     slicerConfig.DiskSpaceCheckWarn,
 				},
 			},
 		},
@@ -308,12 +441,17 @@ func getTestData() []testData {
 					AheadSlices:        1,
 					AheadBlocks:        16,
 					AheadBlockSize:     datasize.MB,
+					InputFormat:        slicerConfig.FormatAuto,
 					InputSizeThreshold: 50 * datasize.MB,
 					Gzip:               true,
 					GzipLevel:          1,
 					GzipConcurrency:    0,
 					GzipBlockSize:      1 * datasize.MB,
 					BufferSize:         20 * datasize.MB,
+					OutputTypes:        slicerConfig.TypesString,
+					ParquetCompression: slicerConfig.CompressionSnappy,
+					SpillBufferSize:    32 * datasize.MB,
+					DiskSpaceCheck:     slicerConfig.DiskSpaceCheckWarn,
 				},
 			},
 		},
@@ -336,12 +474,17 @@ func getTestData() []testData {
 					AheadSlices:        1,
 					AheadBlocks:        16,
 					AheadBlockSize:     datasize.MB,
+					InputFormat:        slicerConfig.FormatAuto,
 					InputSizeThreshold: 50 * datasize.MB,
 					Gzip:               true,
 					GzipLevel:          1,
 					GzipConcurrency:    0,
 					GzipBlockSize:      1 * datasize.MB,
 					BufferSize:         20 * datasize.MB,
+					OutputTypes:        slicerConfig.TypesString,
+					ParquetCompression: slicerConfig.CompressionSnappy,
+					SpillBufferSize:    32 * datasize.MB,
+					DiskSpaceCheck:     slicerConfig.DiskSpaceCheckWarn,
 				},
 			},
 		},
@@ -364,12 +507,18 @@ func getTestData() []testData {
 					AheadSlices:        3,
 					AheadBlocks:        4,
 					AheadBlockSize:     5 * datasize.MB,
+					InputFormat:        slicerConfig.FormatAuto,
 					InputSizeThreshold: 50 * datasize.MB,
 					Gzip:               true,
 					GzipLevel:          1,
 					GzipConcurrency:    0,
 					GzipBlockSize:      1 * datasize.MB,
 					BufferSize:         20 * datasize.MB,
+					OutputTypes:        slicerConfig.TypesString,
+					ParquetCompression: slicerConfig.CompressionSnappy,
+					SpillBufferSize:    32 * datasize.MB,
+					DiskSpaceCheck:     slicerConfig.DiskSpaceCheckWarn,
+					Explicit:           slicerConfig.PoolSettings{AheadBlocks: true, AheadBlockSize: true},
 				},
 			},
 		},
@@ -392,14 +541,31 @@ func getTestData() []testData {
 					AheadSlices:        1,
 					AheadBlocks:        16,
 					AheadBlockSize:     datasize.MB,
+					InputFormat:        slicerConfig.FormatAuto,
 					InputSizeThreshold: 10 * datasize.MB,
 					Gzip:               true,
 					GzipLevel:          1,
 					GzipConcurrency:    0,
 					GzipBlockSize:      1 * datasize.MB,
 					BufferSize:         20 * datasize.MB,
+					OutputTypes:        slicerConfig.TypesString,
+					ParquetCompression: slicerConfig.CompressionSnappy,
+					SpillBufferSize:    32 * datasize.MB,
+					DiskSpaceCheck:     slicerConfig.DiskSpaceCheckWarn,
 				},
 			},
 		},
 	}
 }
+
+func TestProfilingParameters_Config(t *testing.T) {
+	t.Parallel()
+
+	params := ProfilingParameters{CPU: true, Mutex: true, PprofAddr: "localhost:6060"}
+	assert.Equal(t, profiling.Config{
+		CPUProfile:   "/data/out/files/profiling/cpu.pprof",
+		MutexProfile: "/data/out/files/profiling/mutex.pprof",
+		PprofAddr:    "localhost:6060",
+	}, params.Config("/data/out/files/profiling"))
+	assert.False(t, ProfilingParameters{}.Config("/data/out/files/profiling").Enabled())
+}
@@ -2,21 +2,26 @@
 package processor
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 
 	"github.com/dustin/go-humanize"
 
+	"github.com/keboola/processor-split-table/internal/pkg/cgroup"
 	"github.com/keboola/processor-split-table/internal/pkg/kbc"
 	"github.com/keboola/processor-split-table/internal/pkg/log"
 	"github.com/keboola/processor-split-table/internal/pkg/processor/config"
 	"github.com/keboola/processor-split-table/internal/pkg/processor/finder"
+	"github.com/keboola/processor-split-table/internal/pkg/profiling"
 	"github.com/keboola/processor-split-table/internal/pkg/slicer"
 	slicerConfig "github.com/keboola/processor-split-table/internal/pkg/slicer/config"
 	"github.com/keboola/processor-split-table/internal/pkg/utils"
 )
 
-func Run(logger log.Logger) error {
+// Run processes the data dir, the profiling configured by flags takes precedence over the configuration.
+func Run(logger log.Logger, profilingFlags profiling.Config) (err error) {
 	inputDir := kbc.GetInputDir()
 	outputDir := kbc.GetOutputDir()
 
@@ -26,6 +31,28 @@ func Run(logger log.Logger) error {
 		return err
 	}
 
+	// Set GOMAXPROCS and soft memory limit (GOMEMLIMIT) by the cgroup limits, GOMAXPROCS and GOMEMLIMIT envs take precedence
+	cgroup.Apply(logger, cgroup.Detect(), 0, 0)
+
+	// Profiling can be enabled by flags or by the configuration, profiles are written to the "out/files" directory
+	profilingCfg := profilingFlags
+	if !profilingCfg.Enabled() {
+		profilingDir := filepath.Join(outputDir, "files", "profiling")
+		profilingCfg = cfg.Processor.Profiling.Config(profilingDir)
+		if profilingCfg.Enabled() {
+			if err := os.MkdirAll(profilingDir, 0o755); err != nil {
+				return err
+			}
+		}
+	}
+	if profilingCfg.Enabled() {
+		stop, err := profiling.Start(logger, profilingCfg)
+		if err != nil {
+			return err
+		}
+		defer func() { err = errors.Join(err, stop()) }()
+	}
+
 	// Find file nodes
 	nodes, err := finder.FindFilesRecursive(inputDir)
 	if err != nil {
@@ -56,7 +83,12 @@ func Run(logger log.Logger) error {
 		return kbc.UserErrorf("unexpected mode \"%s\".", cfg.Parameters.Mode)
 	}
 
-	if cfg.Parameters.Gzip {
+	switch {
+	case cfg.Parameters.OutputFormat == slicerConfig.FormatParquet:
+		logger.Infof("Parquet output enabled, compression = %s.", cfg.Parameters.ParquetCompression)
+	case cfg.Parameters.OutputFormat == slicerConfig.FormatArrow:
+		logger.Infof("Arrow output enabled.")
+	case cfg.Parameters.Gzip:
 		logger.Infof("Gzip enabled, compression level = %d.", cfg.Parameters.GzipLevel)
 	}
 
@@ -64,12 +96,9 @@ func Run(logger log.Logger) error {
 	for _, node := range nodes {
 		var err error
 		switch node.FileType {
-		case finder.CsvTableSingle:
-			// Slice single CSV file
-			err = slicer.SliceTable(logger, tableDefinition(cfg, node, inputDir, outputDir))
-		case finder.CsvTableSliced:
-			// Re-slice sliced CSV table
-			err = slicer.SliceTable(logger, tableDefinition(cfg, node, inputDir, outputDir))
+		case finder.CsvTableSingle, finder.CsvTableSliced:
+			// Slice single CSV file or re-slice sliced CSV table
+			err = sliceTable(logger, cfg, node, inputDir, outputDir)
 		case finder.Directory:
 			err = utils.Mkdir(filepath.Join(outputDir, node.RelativePath))
 		case finder.File:
@@ -91,8 +120,23 @@ func Run(logger log.Logger) error {
 	return nil
 }
 
+func sliceTable(logger log.Logger, cfg *config.Config, file *finder.FileNode, inputDir, outputDir string) error {
+	table := tableDefinition(cfg, file, inputDir, outputDir)
+
+	// Create parent directory of the files written outside the "tables" directory
+	for _, path := range []string{table.OutPartsMetadataPath, table.OutProfilePath, table.OutResultPath} {
+		if path != "" {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return err
+			}
+		}
+	}
+
+	return slicer.SliceTable(logger, table)
+}
+
 func tableDefinition(cfg *config.Config, file *finder.FileNode, inputDir, outputDir string) slicer.Table {
-	return slicer.Table{
+	table := slicer.Table{
 		Config:          cfg.Parameters,
 		Name:            file.RelativePath,
 		InPath:          filepath.Join(inputDir, file.RelativePath),
@@ -100,4 +144,17 @@ func tableDefinition(cfg *config.Config, file *finder.FileNode, inputDir, output
 		OutPath:         filepath.Join(outputDir, file.RelativePath),
 		OutManifestPath: filepath.Join(outputDir, file.ManifestPath),
 	}
+
+	// Files in the "out/tables" directory are imported as tables, so the metadata are stored to the "out/files".
+	if cfg.Processor.PartsMetadata || len(cfg.Parameters.ZoneMapColumns) > 0 {
+		table.OutPartsMetadataPath = filepath.Join(outputDir, "files", file.RelativePath+".parts.json")
+	}
+	if cfg.Processor.Profile {
+		table.OutProfilePath = filepath.Join(outputDir, "files", file.RelativePath+".profile.json")
+	}
+	if cfg.Processor.Result {
+		table.OutResultPath = filepath.Join(outputDir, "files", file.RelativePath+".result.json")
+	}
+
+	return table
 }
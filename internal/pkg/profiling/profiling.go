@@ -0,0 +1,171 @@
+// Package profiling provides Go runtime profiles of the slicing, written to files and/or served by a pprof listener.
+package profiling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	httpPprof "net/http/pprof"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"time"
+
+	"github.com/keboola/processor-split-table/internal/pkg/log"
+)
+
+// Config of the profiling, each profile is written to the file, if the path is set.
+type Config struct {
+	CPUProfile    string `json:"cpuProfile" mapstructure:"cpuprofile"`
+	HeapProfile   string `json:"heapProfile" mapstructure:"memprofile"`
+	AllocsProfile string `json:"allocsProfile" mapstructure:"allocsprofile"`
+	BlockProfile  string `json:"blockProfile" mapstructure:"blockprofile"`
+	MutexProfile  string `json:"mutexProfile" mapstructure:"mutexprofile"`
+	Trace         string `json:"trace" mapstructure:"trace"`
+	PprofAddr     string `json:"pprofAddr" mapstructure:"pprof-addr"` // optional, for example "localhost:6060"
+}
+
+// Enabled returns true, if at least one profile is written or the pprof listener is started.
+func (c Config) Enabled() bool {
+	return c.CPUProfile != "" ||
+		c.HeapProfile != "" ||
+		c.AllocsProfile != "" ||
+		c.BlockProfile != "" ||
+		c.MutexProfile != "" ||
+		c.Trace != "" ||
+		c.PprofAddr != ""
+}
+
+// Start starts the CPU profile, the execution trace and the pprof listener according to the configuration.
+// The returned stop function stops them and writes the heap, allocs, block and mutex profiles,
+// so they describe the whole run.
+func Start(logger log.Logger, cfg Config) (stop func() error, err error) {
+	var stops []func() error
+	stopAll := func() error {
+		var errs []error
+		for _, fn := range stops {
+			errs = append(errs, fn())
+		}
+		return errors.Join(errs...)
+	}
+
+	// Stop already started profiles on error
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, stopAll())
+		}
+	}()
+
+	// Block and mutex events are not sampled by default
+	if cfg.BlockProfile != "" || cfg.PprofAddr != "" {
+		runtime.SetBlockProfileRate(1)
+		stops = append(stops, func() error {
+			runtime.SetBlockProfileRate(0)
+			return nil
+		})
+	}
+	if cfg.MutexProfile != "" || cfg.PprofAddr != "" {
+		prevFraction := runtime.SetMutexProfileFraction(1)
+		stops = append(stops, func() error {
+			runtime.SetMutexProfileFraction(prevFraction)
+			return nil
+		})
+	}
+
+	if cfg.CPUProfile != "" {
+		f, err := os.Create(cfg.CPUProfile)
+		if err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			return nil, errors.Join(err, f.Close())
+		}
+		stops = append(stops, func() error {
+			pprof.StopCPUProfile()
+			return f.Close()
+		})
+	}
+
+	if cfg.Trace != "" {
+		f, err := os.Create(cfg.Trace)
+		if err != nil {
+			return nil, err
+		}
+		if err := trace.Start(f); err != nil {
+			return nil, errors.Join(err, f.Close())
+		}
+		stops = append(stops, func() error {
+			trace.Stop()
+			return f.Close()
+		})
+	}
+
+	// Serve profiles via HTTP, the listener is created synchronously, so an invalid address is reported
+	if cfg.PprofAddr != "" {
+		listener, err := net.Listen("tcp", cfg.PprofAddr)
+		if err != nil {
+			return nil, err
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/", httpPprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", httpPprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", httpPprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", httpPprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", httpPprof.Trace)
+		srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Warnf(`Pprof server failed: %s`, err)
+			}
+		}()
+		logger.Infof(`Pprof is served on "http://%s/debug/pprof/".`, listener.Addr())
+		stops = append(stops, func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return srv.Shutdown(ctx)
+		})
+	}
+
+	// Snapshot profiles are written on stop, before the sampling rates are reset
+	var snapshots []func() error
+	for _, s := range []struct{ name, path string }{
+		{name: "heap", path: cfg.HeapProfile},
+		{name: "allocs", path: cfg.AllocsProfile},
+		{name: "block", path: cfg.BlockProfile},
+		{name: "mutex", path: cfg.MutexProfile},
+	} {
+		if s.path != "" {
+			s := s
+			snapshots = append(snapshots, func() error { return writeProfile(s.name, s.path) })
+		}
+	}
+	stops = append(snapshots, stops...)
+
+	return stopAll, nil
+}
+
+// writeProfile writes the named runtime profile to the file.
+func writeProfile(name, path string) (err error) {
+	// Get up-to-date statistics of the heap
+	if name == "heap" || name == "allocs" {
+		runtime.GC()
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf(`cannot close file "%s": %w`, path, closeErr)
+		}
+	}()
+
+	if err := pprof.Lookup(name).WriteTo(f, 0); err != nil {
+		return fmt.Errorf(`cannot write %s profile "%s": %w`, name, path, err)
+	}
+	return nil
+}
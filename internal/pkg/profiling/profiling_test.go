@@ -0,0 +1,71 @@
+package profiling
+
+import (
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestConfig_Enabled(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, Config{}.Enabled())
+	assert.True(t, Config{HeapProfile: "heap.pprof"}.Enabled())
+	assert.True(t, Config{PprofAddr: "localhost:6060"}.Enabled())
+}
+
+// TestStart is not parallel, the CPU profile and the execution trace can be started only once per process.
+func TestStart(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core).Sugar()
+	dir := t.TempDir()
+
+	cfg := Config{
+		CPUProfile:    filepath.Join(dir, "cpu.pprof"),
+		HeapProfile:   filepath.Join(dir, "heap.pprof"),
+		AllocsProfile: filepath.Join(dir, "allocs.pprof"),
+		BlockProfile:  filepath.Join(dir, "block.pprof"),
+		MutexProfile:  filepath.Join(dir, "mutex.pprof"),
+		Trace:         filepath.Join(dir, "trace.out"),
+		PprofAddr:     "127.0.0.1:0",
+	}
+	stop, err := Start(logger, cfg)
+	require.NoError(t, err)
+
+	// Get the heap profile via HTTP
+	require.Equal(t, 1, logs.Len())
+	addr := regexp.MustCompile(`"(http://[^"]+)"`).FindStringSubmatch(logs.All()[0].Message)
+	require.Len(t, addr, 2)
+	resp, err := http.Get(addr[1] + "heap") // nolint: noctx
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, body)
+
+	// All profiles are written on stop
+	require.NoError(t, stop())
+	for _, path := range []string{cfg.CPUProfile, cfg.HeapProfile, cfg.AllocsProfile, cfg.BlockProfile, cfg.MutexProfile, cfg.Trace} {
+		assert.FileExists(t, path)
+	}
+
+	// Profiles can be started again
+	stop, err = Start(logger, Config{CPUProfile: filepath.Join(dir, "cpu2.pprof")})
+	require.NoError(t, err)
+	require.NoError(t, stop())
+}
+
+func TestStart_InvalidPath(t *testing.T) {
+	t.Parallel()
+
+	_, err := Start(zap.NewNop().Sugar(), Config{HeapProfile: "heap.pprof", Trace: filepath.Join(t.TempDir(), "missing", "trace.out")})
+	assert.Error(t, err)
+}
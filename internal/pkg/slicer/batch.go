@@ -0,0 +1,95 @@
+package slicer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/c2h5oh/datasize"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/log"
+)
+
+// SliceTables slices the tables by a bounded pool of workers, at most "concurrency" tables are processed in parallel.
+// A failed table does not stop the others, the summary is logged at the end.
+//
+// The returned error is consolidated:
+//   - an application error, if at least one table failed with an unexpected error,
+//   - a user error, if all failed tables failed with a user error,
+//   - an exit code error with the highest exit code, if some tables were skipped by the InputSizeLowExitCode,
+//   - nil otherwise.
+func SliceTables(logger log.Logger, tables []Table, concurrency int) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// Workers share CPU threads, if the gzip concurrency is auto, and the memory limit
+	cpuBudget := runtime.GOMAXPROCS(0) / concurrency
+	if cpuBudget < 1 {
+		cpuBudget = 1
+	}
+	memoryBudget := datasize.ByteSize(debug.SetMemoryLimit(-1))
+	if memoryBudget < math.MaxInt64 {
+		memoryBudget /= datasize.ByteSize(concurrency)
+	}
+
+	logger.Infof("Slicing %d tables, concurrency = %d.", len(tables), concurrency)
+
+	// Slice tables, errors are collected, so a failed table does not stop the others
+	errs := make([]error, len(tables))
+	grp := &errgroup.Group{}
+	grp.SetLimit(concurrency)
+	for i, table := range tables {
+		i, table := i, table
+		table.CPUBudget = cpuBudget
+		table.MemoryBudget = memoryBudget
+		grp.Go(func() error {
+			errs[i] = SliceTable(logger, table)
+			var exitCodeErr *kbc.ExitCodeError
+			if errs[i] != nil && !errors.As(errs[i], &exitCodeErr) {
+				logger.Errorf(`Table "%s" failed: %s`, table.Name, errs[i])
+			}
+			return nil
+		})
+	}
+	_ = grp.Wait()
+
+	// Consolidate results
+	var succeeded, skipped, failed, exitCode int
+	userErrorsOnly := true
+	for _, err := range errs {
+		var exitCodeErr *kbc.ExitCodeError
+		var userErr *kbc.UserError
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.As(err, &exitCodeErr):
+			skipped++
+			if code := exitCodeErr.ExitCode(); code > exitCode {
+				exitCode = code
+			}
+		default:
+			failed++
+			if !errors.As(err, &userErr) {
+				userErrorsOnly = false
+			}
+		}
+	}
+
+	logger.Infof("Batch finished: %d tables, %d succeeded, %d skipped, %d failed.", len(tables), succeeded, skipped, failed)
+
+	switch {
+	case failed > 0 && userErrorsOnly:
+		return kbc.UserErrorf(`%d of %d tables failed`, failed, len(tables))
+	case failed > 0:
+		return fmt.Errorf(`%d of %d tables failed`, failed, len(tables))
+	case exitCode > 0:
+		return kbc.ExitCodeErrorf(exitCode, `%d of %d tables skipped`, skipped, len(tables))
+	default:
+		return nil
+	}
+}
@@ -12,8 +12,10 @@ type Parser struct {
 	row             []byte
 	length          int
 	insideEnclosure bool
-	columns         []string
-	current         []byte
+	buffer          []byte   // unescaped values of all fields
+	ends            []int    // end offset of each field in the buffer
+	fields          [][]byte // reused output of the Fields method
+	start           int      // start offset of the current field in the buffer
 	index           int
 }
 
@@ -23,31 +25,59 @@ func NewParser(delimiter byte, enclosure byte) *Parser {
 
 // Parse CSV columns from row.
 func (p *Parser) Parse(row []byte) ([]string, error) {
+	fields, err := p.Fields(row)
+	if err != nil || fields == nil {
+		return nil, err
+	}
+
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = string(field)
+	}
+	return columns, nil
+}
+
+// Fields parses CSV fields from the row without allocating strings.
+// Returned values are valid only until the next call, the internal buffers are reused.
+func (p *Parser) Fields(row []byte) ([][]byte, error) {
 	p.row = bytes.TrimRight(row, "\n")
 	p.length = len(p.row)
-	p.current = nil
+	p.buffer = p.buffer[:0]
+	p.ends = p.ends[:0]
+	p.start = 0
 	p.index = 0
 	p.insideEnclosure = false
 
 	// Iterate over chars
 	for p.index < p.length {
-		err := p.processChar(row[p.index])
+		err := p.processChar(p.row[p.index])
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	// Flush rest if not empty row
-	if p.length > 0 {
-		p.flushColumn()
+	// Empty row
+	if p.length == 0 {
+		return nil, nil
 	}
 
+	// Flush rest
+	p.flushColumn()
+
 	// Check if enclosure is ended
 	if p.insideEnclosure {
 		return nil, kbc.UserErrorf("reached end of the row, but enclosure is not ended")
 	}
 
-	return p.columns, nil
+	// Map offsets to the fields, the buffer is no longer modified
+	p.fields = p.fields[:0]
+	start := 0
+	for _, end := range p.ends {
+		p.fields = append(p.fields, p.buffer[start:end:end])
+		start = end
+	}
+
+	return p.fields, nil
 }
 
 func (p *Parser) processChar(char byte) error {
@@ -56,24 +86,25 @@ func (p *Parser) processChar(char byte) error {
 		// Column found
 		p.flushColumn()
 	case p.isEnclosure(char):
-		// If next char is enclosure -> escaped enclosure
-		if p.isNextCharEnclosure() {
+		// If next char is enclosure inside enclosure -> escaped enclosure.
+		// Outside enclosure, two enclosures are an empty value.
+		if p.insideEnclosure && p.isNextCharEnclosure() {
 			// Write one enclosure to column value
-			p.current = append(p.current, char)
+			p.buffer = append(p.buffer, char)
 			// Skip next char
 			p.index += 2
 			return nil
 		}
 
-		if !p.insideEnclosure && len(p.current) > 0 {
-			return kbc.UserErrorf("unexpected token \"%s\" before enclosure at position %d", p.current, p.index+1)
+		if !p.insideEnclosure && len(p.buffer) > p.start {
+			return kbc.UserErrorf("unexpected token \"%s\" before enclosure at position %d", p.buffer[p.start:], p.index+1)
 		}
 
 		// Invert state
 		p.insideEnclosure = !p.insideEnclosure
 	default:
 		// Char is part of the column value
-		p.current = append(p.current, char)
+		p.buffer = append(p.buffer, char)
 	}
 
 	p.index++
@@ -82,8 +113,8 @@ func (p *Parser) processChar(char byte) error {
 }
 
 func (p *Parser) flushColumn() {
-	p.columns = append(p.columns, string(p.current))
-	p.current = nil
+	p.ends = append(p.ends, len(p.buffer))
+	p.start = len(p.buffer)
 }
 
 func (p *Parser) isDelimiter(char byte) bool {
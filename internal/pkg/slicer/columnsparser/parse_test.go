@@ -26,6 +26,23 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestFields_ReuseParser(t *testing.T) {
+	t.Parallel()
+
+	parser := NewParser(',', '"')
+	for _, data := range GetTestParseHeaderData() {
+		fields, err := parser.Fields(data.input)
+		var columns []string
+		for _, field := range fields {
+			columns = append(columns, string(field))
+		}
+		assert.Equal(t, data.expectedColumns, columns, data.comment)
+		if data.expectedErr != "" && assert.Error(t, err) {
+			assert.Equal(t, data.expectedErr, err.Error(), data.comment)
+		}
+	}
+}
+
 func GetTestParseHeaderData() []testData {
 	return []testData{
 		{
@@ -78,6 +95,11 @@ func GetTestParseHeaderData() []testData {
 			input:           []byte(","),
 			expectedColumns: []string{"", ""},
 		},
+		{
+			comment:         "Empty enclosed columns",
+			input:           []byte("\"\",\"\"\"\",\"\""),
+			expectedColumns: []string{"", "\"", ""},
+		},
 		{
 			comment:         "Unfinished enclosure - 1",
 			input:           []byte("\""),
@@ -16,8 +16,54 @@ const (
 	ModeSlices
 )
 
+const (
+	DeduplicationNone Deduplication = iota
+	DeduplicationFirst
+	DeduplicationLast
+)
+
+const (
+	FormatCSV Format = iota
+	FormatParquet
+	FormatJSONL
+	FormatArrow
+	// FormatAuto detects format of the input table by the extension, it cannot be used for the output.
+	FormatAuto
+)
+
+const (
+	TypesString Types = iota
+	TypesInferred
+)
+
+const (
+	CompressionSnappy Compression = iota
+	CompressionNone
+	CompressionZstd
+)
+
+// Policies of the disk space check, if the estimated output size exceeds the free space.
+const (
+	DiskSpaceCheckWarn DiskSpaceCheck = iota
+	DiskSpaceCheckFail
+	DiskSpaceCheckNone
+)
+
 type Mode uint
 
+// Format of the input table or the output slices.
+type Format uint
+
+type Deduplication uint
+
+// Types of the columns in a typed output format.
+type Types uint
+
+// Compression codec of the parquet format.
+type Compression uint
+
+type DiskSpaceCheck uint
+
 type ByteSize = datasize.ByteSize
 
 type Config struct {
@@ -44,6 +90,9 @@ type Config struct {
 	// AheadBlockSize specifies size of a one read ahead block.
 	AheadBlockSize datasize.ByteSize `json:"aheadBlockSize" mapstructure:"ahead-block-size" validate:"min=32768"` // min 32KB
 
+	// InputFormat of the table, "csv", "parquet", "jsonl" or "auto" detected by the extension.
+	InputFormat Format `json:"inputFormat" mapstructure:"input-format"`
+
 	// InputSizeThreshold at least one slice must exceed the threshold, otherwise the table is copied without modification.
 	InputSizeThreshold datasize.ByteSize `json:"inputSizeThreshold" mapstructure:"input-size-threshold"` // 0 = no threshold
 
@@ -56,6 +105,63 @@ type Config struct {
 	// BufferSize is used if GZIP is disabled.
 	// If Gzip is enabled, the total buffer size is GzipConcurrency * GzipBlockSize.
 	BufferSize datasize.ByteSize `json:"bufferSize" mapstructure:"buffer-size" validate:"min=32768"`
+
+	// OutputFormat of the slices, CSV by default.
+	OutputFormat Format `json:"outputFormat" mapstructure:"output-format"`
+	// OutputTypes of the columns in the "parquet", "jsonl" and "arrow" formats, "string" or "inferred" from the input values.
+	OutputTypes Types `json:"outputTypes" mapstructure:"output-types"`
+	// ParquetCompression codec of the parquet slices, the Gzip option is ignored for the parquet format.
+	ParquetCompression Compression `json:"parquetCompression" mapstructure:"parquet-compression"`
+
+	// Deduplication of rows by the manifest "primary_key", the first or the last occurrence of each key is kept.
+	Deduplication Deduplication `json:"deduplication" mapstructure:"deduplication"`
+
+	// SortBy columns, if set, the table is sorted by the columns before slicing.
+	SortBy []string `json:"sortBy" mapstructure:"sort-by"`
+
+	// ZoneMapColumns, if set, min/max values of the columns in each slice are written to the parts metadata.
+	ZoneMapColumns []string `json:"zoneMapColumns" mapstructure:"zone-map-columns"`
+
+	// SpillBufferSize is the maximum size of rows held in memory by the deduplication and the sort, the rest is spilled to disk.
+	// It also limits the size of the Sample rows, they are not spilled.
+	SpillBufferSize datasize.ByteSize `json:"spillBufferSize" mapstructure:"spill-buffer-size" validate:"min=32768"`
+	// TempDir for spilled rows, the system temp dir is used if empty.
+	TempDir string `json:"tempDir" mapstructure:"temp-dir"`
+
+	// InferTypes of the columns from the values, the result is written to the output manifest "column_metadata".
+	// If the OutputTypes are TypesInferred, the types of the output format are reused.
+	InferTypes bool `json:"inferTypes" mapstructure:"infer-types"`
+
+	// DiskSpaceCheck policy, if the estimated output size exceeds the free space of the output filesystem: "fail", "warn" or "none".
+	DiskSpaceCheck DiskSpaceCheck `json:"diskSpaceCheck" mapstructure:"disk-space-check"`
+
+	// Sample of the rows, disabled by default.
+	Sample SampleConfig `json:"sample" mapstructure:",squash"`
+
+	// Explicit buffer pool settings, set by the user, they are not reduced by the memory plan.
+	// It is set from the JSON keys by UnmarshalJSON, the CLI sets it from the flags, ENVs and the config file.
+	Explicit PoolSettings `json:"-" mapstructure:"-"`
+}
+
+// PoolSettings marks the settings of the buffer pools, see Config.Explicit.
+type PoolSettings struct {
+	GzipConcurrency bool
+	GzipBlockSize   bool
+	BufferSize      bool
+	AheadBlocks     bool
+	AheadBlockSize  bool
+	SpillBufferSize bool
+}
+
+// SampleConfig - only one of Fraction and Rows can be set.
+// The sample is deterministic, the same input and Seed produce the same sample.
+type SampleConfig struct {
+	// Fraction of the rows to keep, each row is kept with the probability.
+	Fraction float64 `json:"fraction" mapstructure:"sample-fraction" validate:"min=0,max=1"` // 0 = disabled
+	// Rows is a fixed number of rows to keep, selected by the reservoir sampling, they must fit into the SpillBufferSize.
+	Rows uint64 `json:"rows" mapstructure:"sample-rows"` // 0 = disabled
+	// Seed of the random generator.
+	Seed int64 `json:"seed" mapstructure:"sample-seed"`
 }
 
 type LogIntervalConfig struct {
@@ -79,12 +185,19 @@ func Default() Config {
 		AheadSlices:        1,
 		AheadBlocks:        16,
 		AheadBlockSize:     1 * datasize.MB,
+		InputFormat:        FormatAuto,
 		InputSizeThreshold: 50 * datasize.MB,
 		Gzip:               true,
 		GzipLevel:          1,                // 1 - BestSpeed, 9 - BestCompression
 		GzipConcurrency:    0,                // 0 = auto = number of CPU threads
 		GzipBlockSize:      1 * datasize.MB,  // so total buffer size is by default: GzipConcurrency (number of CPU threads) * GzipBlockSize
 		BufferSize:         20 * datasize.MB, // it is used if GZIP is disabled
+		OutputFormat:       FormatCSV,
+		OutputTypes:        TypesString,
+		ParquetCompression: CompressionSnappy,
+		Deduplication:      DeduplicationNone,
+		SpillBufferSize:    32 * datasize.MB,
+		DiskSpaceCheck:     DiskSpaceCheckWarn,
 	}
 }
 
@@ -113,7 +226,18 @@ func (v *Config) UnmarshalJSON(data []byte) error {
 
 	// Decode to the struct, skip UnmarshalJSON implementation
 	type _c Config
-	return json.Unmarshal(data, (*_c)(v))
+	if err := json.Unmarshal(data, (*_c)(v)); err != nil {
+		return err
+	}
+
+	// Settings present in the JSON are explicit
+	_, v.Explicit.GzipConcurrency = m["gzipConcurrency"]
+	_, v.Explicit.GzipBlockSize = m["gzipBlockSize"]
+	_, v.Explicit.BufferSize = m["bufferSize"]
+	_, v.Explicit.AheadBlocks = m["aheadBlocks"]
+	_, v.Explicit.AheadBlockSize = m["aheadBlockSize"]
+	_, v.Explicit.SpillBufferSize = m["spillBufferSize"]
+	return nil
 }
 
 func (m Mode) String() string {
@@ -158,3 +282,241 @@ func (m *Mode) UnmarshalText(b []byte) error {
 
 	return nil
 }
+
+func (f Format) String() string {
+	str, err := f.StringOrErr()
+	if err != nil {
+		panic(err)
+	}
+	return str
+}
+
+func (f Format) StringOrErr() (string, error) {
+	switch f {
+	case FormatCSV:
+		return "csv", nil
+	case FormatParquet:
+		return "parquet", nil
+	case FormatJSONL:
+		return "jsonl", nil
+	case FormatArrow:
+		return "arrow", nil
+	case FormatAuto:
+		return "auto", nil
+	default:
+		return "", fmt.Errorf(`unexpected value "%v" for "format"`, uint(f))
+	}
+}
+
+// Extension of the slice file, without compression suffix.
+func (f Format) Extension() string {
+	switch f {
+	case FormatParquet:
+		return ".parquet"
+	case FormatJSONL:
+		return ".jsonl"
+	case FormatArrow:
+		return ".arrow"
+	default:
+		return ""
+	}
+}
+
+func (f Format) MarshalText() ([]byte, error) {
+	str, err := f.StringOrErr()
+	return []byte(str), err
+}
+
+func (f *Format) UnmarshalText(b []byte) error {
+	// Convert "inputFormat" or "outputFormat" string value to numeric constant
+	str := string(b)
+	switch str {
+	case "csv", "":
+		*f = FormatCSV
+	case "parquet":
+		*f = FormatParquet
+	case "jsonl":
+		*f = FormatJSONL
+	case "arrow":
+		*f = FormatArrow
+	case "auto":
+		*f = FormatAuto
+	default:
+		return fmt.Errorf(`unexpected value "%s" for "format", use "csv", "parquet", "jsonl", "arrow" or "auto" for the input`, str)
+	}
+
+	return nil
+}
+
+func (d Deduplication) String() string {
+	str, err := d.StringOrErr()
+	if err != nil {
+		panic(err)
+	}
+	return str
+}
+
+func (d Deduplication) StringOrErr() (string, error) {
+	switch d {
+	case DeduplicationNone:
+		return "none", nil
+	case DeduplicationFirst:
+		return "first", nil
+	case DeduplicationLast:
+		return "last", nil
+	default:
+		return "", fmt.Errorf(`unexpected value "%v" for "deduplication"`, uint(d))
+	}
+}
+
+func (d Deduplication) MarshalText() ([]byte, error) {
+	str, err := d.StringOrErr()
+	return []byte(str), err
+}
+
+func (d *Deduplication) UnmarshalText(b []byte) error {
+	// Convert "deduplication" string value to numeric constant
+	str := string(b)
+	switch str {
+	case "none", "":
+		*d = DeduplicationNone
+	case "first":
+		*d = DeduplicationFirst
+	case "last":
+		*d = DeduplicationLast
+	default:
+		return fmt.Errorf(`unexpected value "%s" for "deduplication", use "none", "first" or "last"`, str)
+	}
+
+	return nil
+}
+
+func (t Types) String() string {
+	str, err := t.StringOrErr()
+	if err != nil {
+		panic(err)
+	}
+	return str
+}
+
+func (t Types) StringOrErr() (string, error) {
+	switch t {
+	case TypesString:
+		return "string", nil
+	case TypesInferred:
+		return "inferred", nil
+	default:
+		return "", fmt.Errorf(`unexpected value "%v" for "outputTypes"`, uint(t))
+	}
+}
+
+func (t Types) MarshalText() ([]byte, error) {
+	str, err := t.StringOrErr()
+	return []byte(str), err
+}
+
+func (t *Types) UnmarshalText(b []byte) error {
+	// Convert "outputTypes" string value to numeric constant
+	str := string(b)
+	switch str {
+	case "string", "":
+		*t = TypesString
+	case "inferred":
+		*t = TypesInferred
+	default:
+		return fmt.Errorf(`unexpected value "%s" for "outputTypes", use "string" or "inferred"`, str)
+	}
+
+	return nil
+}
+
+func (c Compression) String() string {
+	str, err := c.StringOrErr()
+	if err != nil {
+		panic(err)
+	}
+	return str
+}
+
+func (c Compression) StringOrErr() (string, error) {
+	switch c {
+	case CompressionSnappy:
+		return "snappy", nil
+	case CompressionNone:
+		return "none", nil
+	case CompressionZstd:
+		return "zstd", nil
+	default:
+		return "", fmt.Errorf(`unexpected value "%v" for "parquetCompression"`, uint(c))
+	}
+}
+
+func (c Compression) MarshalText() ([]byte, error) {
+	str, err := c.StringOrErr()
+	return []byte(str), err
+}
+
+func (c *Compression) UnmarshalText(b []byte) error {
+	// Convert "parquetCompression" string value to numeric constant
+	str := string(b)
+	switch str {
+	case "snappy", "":
+		*c = CompressionSnappy
+	case "none":
+		*c = CompressionNone
+	case "zstd":
+		*c = CompressionZstd
+	default:
+		return fmt.Errorf(`unexpected value "%s" for "parquetCompression", use "none", "snappy" or "zstd"`, str)
+	}
+
+	return nil
+}
+
+func (d DiskSpaceCheck) String() string {
+	str, err := d.StringOrErr()
+	if err != nil {
+		panic(err)
+	}
+	return str
+}
+
+func (d DiskSpaceCheck) StringOrErr() (string, error) {
+	switch d {
+	case DiskSpaceCheckWarn:
+		return "warn", nil
+	case DiskSpaceCheckFail:
+		return "fail", nil
+	case DiskSpaceCheckNone:
+		return "none", nil
+	default:
+		return "", fmt.Errorf(`unexpected value "%v" for "diskSpaceCheck"`, uint(d))
+	}
+}
+
+func (d DiskSpaceCheck) MarshalText() ([]byte, error) {
+	str, err := d.StringOrErr()
+	return []byte(str), err
+}
+
+func (d *DiskSpaceCheck) UnmarshalText(b []byte) error {
+	// Convert "diskSpaceCheck" string value to numeric constant
+	str := string(b)
+	switch str {
+	case "warn", "":
+		*d = DiskSpaceCheckWarn
+	case "fail":
+		*d = DiskSpaceCheckFail
+	case "none":
+		*d = DiskSpaceCheckNone
+	default:
+		return fmt.Errorf(`unexpected value "%s" for "diskSpaceCheck", use "fail", "warn" or "none"`, str)
+	}
+
+	return nil
+}
+
+// Enabled returns true if the sampling is configured.
+func (v SampleConfig) Enabled() bool {
+	return v.Fraction > 0 || v.Rows > 0
+}
@@ -0,0 +1,297 @@
+// Package dedup provides deduplication of rows by the primary key.
+//
+// Rows are kept in memory while they fit into the configured SpillBufferSize.
+// Then they are distributed by the hash of the key to partitions stored in temporary files,
+// so all occurrences of a key are in the same partition, and the partitions are deduplicated one by one.
+// A partition that is still too large is partitioned again with a different hash seed.
+// The number of partitions created at once is limited, because each partition is an open file with a write buffer.
+//
+// The order of the rows is preserved, if all rows fit into the memory.
+// Otherwise, the order is preserved only within a partition.
+package dedup
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+
+	"github.com/c2h5oh/datasize"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/columnsparser"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/pipeline"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/spill"
+)
+
+const (
+	// entryOverhead is an estimated memory usage of one entry in the set, without the key and the row.
+	entryOverhead = 96
+	// minPartitions is the minimum number of partitions, when the rows are spilled to disk.
+	minPartitions = 4
+	// maxPartitions limits the fan-out, larger partitions are partitioned again.
+	maxPartitions = 64
+	// maxBuffersRatio is the maximum part of the memory used by the write buffers of the partitions.
+	maxBuffersRatio = 4
+	// maxDepth limits repeated partitioning, it doesn't help if there are many rows with the same key.
+	maxDepth = 4
+)
+
+// Deduplicator keeps only the first or the last occurrence of each primary key.
+type Deduplicator struct {
+	next         pipeline.Writer
+	mode         config.Deduplication
+	tempDir      string
+	maxMemory    datasize.ByteSize
+	expectedSize datasize.ByteSize
+	parser       *columnsparser.Parser
+	keyColumns   []int
+	key          []byte
+	record       []byte
+
+	set        *set
+	partitions []*spill.File // nil, until the rows are spilled to disk
+	files      []*spill.File // all created partitions, they are removed by the Abort
+
+	inRows  uint64
+	outRows uint64
+}
+
+// New creates the Deduplicator stage, expectedSize is the total input size, 0 if it is unknown.
+func New(cfg config.Config, next pipeline.Writer, columns, primaryKey []string, delimiter, enclosure byte, expectedSize datasize.ByteSize) (*Deduplicator, error) {
+	if len(primaryKey) == 0 {
+		return nil, kbc.UserErrorf(`deduplication requires the manifest "primary_key"`)
+	}
+
+	keyColumns, err := pipeline.ColumnIndexes(columns, primaryKey)
+	if err != nil {
+		return nil, kbc.UserErrorf("invalid primary key: %w", err)
+	}
+
+	return &Deduplicator{
+		next:         next,
+		mode:         cfg.Deduplication,
+		tempDir:      cfg.TempDir,
+		maxMemory:    cfg.SpillBufferSize,
+		expectedSize: expectedSize,
+		parser:       columnsparser.NewParser(delimiter, enclosure),
+		keyColumns:   keyColumns,
+		set:          newSet(cfg.Deduplication),
+	}, nil
+}
+
+func (d *Deduplicator) Write(row []byte) error {
+	d.inRows++
+
+	// Get key
+	fields, err := d.parser.Fields(row)
+	if err != nil {
+		return fmt.Errorf("cannot parse primary key of the row %d: %w", d.inRows, err)
+	}
+	d.key = pipeline.AppendKey(d.key[:0], fields, d.keyColumns)
+
+	// Rows are already spilled to disk
+	if d.partitions != nil {
+		return d.writeToPartition(d.partitions, 0, d.key, row)
+	}
+
+	// Keep rows in memory, while they fit
+	d.set.Add(string(d.key), pipeline.CopyRow(row))
+	if d.set.Size() > d.maxMemory {
+		return d.spill()
+	}
+
+	return nil
+}
+
+// Close writes deduplicated rows to the next stage and closes it.
+// On an error, all remaining partitions are removed.
+func (d *Deduplicator) Close() (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, d.Abort())
+		}
+	}()
+
+	if d.partitions == nil {
+		if err := d.flushSet(); err != nil {
+			return err
+		}
+	} else {
+		if err := rewindPartitions(d.partitions); err != nil {
+			return err
+		}
+		for _, partition := range d.partitions {
+			if err := d.processPartition(partition, 1); err != nil {
+				return err
+			}
+		}
+		d.partitions = nil
+		d.files = nil
+	}
+
+	return d.next.Close()
+}
+
+// Abort removes all remaining partitions, if the slicing fails.
+func (d *Deduplicator) Abort() error {
+	var errs []error
+	for _, file := range d.files {
+		if err := file.Remove(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	d.partitions, d.files = nil, nil
+	return errors.Join(errs...)
+}
+
+// Duplicates returns number of removed rows.
+func (d *Deduplicator) Duplicates() uint64 {
+	return d.inRows - d.outRows
+}
+
+// spill moves all rows from the memory to the partitions.
+func (d *Deduplicator) spill() (err error) {
+	if d.partitions, err = d.createPartitions(d.partitionsCount(d.expectedSize)); err != nil {
+		return err
+	}
+
+	for _, e := range d.set.entries {
+		if e.row != nil {
+			if err := d.writeToPartition(d.partitions, 0, []byte(e.key), e.row); err != nil {
+				return err
+			}
+		}
+	}
+
+	d.set.Reset()
+	return nil
+}
+
+func (d *Deduplicator) processPartition(partition *spill.File, depth int) (err error) {
+	defer func() {
+		if removeErr := partition.Remove(); err == nil && removeErr != nil {
+			err = removeErr
+		}
+	}()
+
+	// The partition is too large, partition it again with a different seed
+	if partition.Size() > d.maxMemory && depth < maxDepth {
+		subPartitions, err := d.createPartitions(d.partitionsCount(partition.Size()))
+		if err != nil {
+			return err
+		}
+		if err := readPartition(partition, func(key, row []byte) error {
+			return d.writeToPartition(subPartitions, depth, key, row)
+		}); err != nil {
+			return err
+		}
+		if err := rewindPartitions(subPartitions); err != nil {
+			return err
+		}
+		for _, subPartition := range subPartitions {
+			if err := d.processPartition(subPartition, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Deduplicate the partition in memory
+	if err := readPartition(partition, func(key, row []byte) error {
+		d.set.Add(string(key), append([]byte(nil), row...))
+		return nil
+	}); err != nil {
+		return err
+	}
+	return d.flushSet()
+}
+
+func (d *Deduplicator) flushSet() error {
+	for _, e := range d.set.entries {
+		if e.row != nil {
+			if err := d.next.Write(e.row); err != nil {
+				return err
+			}
+			d.outRows++
+		}
+	}
+	d.set.Reset()
+	return nil
+}
+
+// partitionsCount returns the number of partitions for the size, 0 if the size is unknown.
+// Each partition should fit into the memory, but the fan-out is limited by the maxPartitions
+// and by the memory of the write buffers, a larger partition is partitioned again in the next pass.
+func (d *Deduplicator) partitionsCount(size datasize.ByteSize) int {
+	count := int(math.Ceil(float64(size) / float64(d.maxMemory)))
+	limit := min(maxPartitions, int(d.maxMemory/maxBuffersRatio/spill.BufferSize))
+	return max(minPartitions, min(count, limit))
+}
+
+func (d *Deduplicator) createPartitions(count int) ([]*spill.File, error) {
+	out := make([]*spill.File, count)
+	for i := range out {
+		file, err := spill.Create(d.tempDir)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = file
+		d.files = append(d.files, file)
+	}
+	return out, nil
+}
+
+// rewindPartitions flushes all partitions before the first one is processed, so the write buffers are released.
+func rewindPartitions(partitions []*spill.File) error {
+	for _, partition := range partitions {
+		if err := partition.Rewind(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeToPartition writes the key and the row as one record, so the key doesn't have to be parsed again.
+// The record buffer is reused, the spill file copies the record to its write buffer.
+// The row always ends with a new line, see pipeline.CopyRow, because the order of the rows is changed.
+func (d *Deduplicator) writeToPartition(partitions []*spill.File, seed int, key, row []byte) error {
+	d.record = binary.AppendUvarint(d.record[:0], uint64(len(key)))
+	d.record = append(d.record, key...)
+	d.record = append(d.record, row...)
+	if len(row) == 0 || row[len(row)-1] != '\n' {
+		d.record = append(d.record, '\n')
+	}
+	return partitions[partitionIndex(key, seed, len(partitions))].Write(d.record)
+}
+
+func readPartition(partition *spill.File, fn func(key, row []byte) error) error {
+	for {
+		record, err := partition.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		keyLength, n := binary.Uvarint(record)
+		if n <= 0 {
+			return fmt.Errorf("invalid record in the spill file")
+		}
+		key := record[n : n+int(keyLength)]
+		row := record[n+int(keyLength):]
+		if err := fn(key, row); err != nil {
+			return err
+		}
+	}
+}
+
+func partitionIndex(key []byte, seed int, count int) int {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte{byte(seed)})
+	_, _ = h.Write(key)
+	return int(h.Sum64() % uint64(count))
+}
@@ -0,0 +1,253 @@
+package dedup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+)
+
+type rowsCollector struct {
+	rows   []string
+	closed bool
+}
+
+func (c *rowsCollector) Write(row []byte) error {
+	c.rows = append(c.rows, string(row))
+	return nil
+}
+
+func (c *rowsCollector) Close() error {
+	c.closed = true
+	return nil
+}
+
+type failingWriter struct {
+	rows   int
+	failAt int
+}
+
+func (w *failingWriter) Write([]byte) error {
+	w.rows++
+	if w.rows >= w.failAt {
+		return errors.New("some error")
+	}
+	return nil
+}
+
+func (w *failingWriter) Close() error {
+	return nil
+}
+
+func TestDeduplicator_InMemory(t *testing.T) {
+	t.Parallel()
+
+	rows := []string{
+		"\"1\",\"a\"\n",
+		"\"2\",\"b\"\n",
+		"\"1\",\"c\"\n",
+		"\"3\",\"d\"\n",
+		"\"2\",\"e\"", // last row without new line
+	}
+
+	cases := []struct {
+		mode     config.Deduplication
+		expected []string
+	}{
+		{
+			mode:     config.DeduplicationFirst,
+			expected: []string{"\"1\",\"a\"\n", "\"2\",\"b\"\n", "\"3\",\"d\"\n"},
+		},
+		{
+			mode:     config.DeduplicationLast,
+			expected: []string{"\"1\",\"c\"\n", "\"3\",\"d\"\n", "\"2\",\"e\"\n"},
+		},
+	}
+
+	for _, tc := range cases {
+		cfg := config.Default()
+		cfg.Deduplication = tc.mode
+		cfg.TempDir = t.TempDir()
+
+		out := &rowsCollector{}
+		d, err := New(cfg, out, []string{"id", "value"}, []string{"id"}, ',', '"', 0)
+		require.NoError(t, err)
+		for _, row := range rows {
+			require.NoError(t, d.Write([]byte(row)))
+		}
+		require.NoError(t, d.Close())
+
+		assert.Equal(t, tc.expected, out.rows, tc.mode.String())
+		assert.True(t, out.closed)
+		assert.Equal(t, uint64(2), d.Duplicates())
+		assertNoSpillFiles(t, cfg.TempDir)
+	}
+}
+
+func TestDeduplicator_Spilled(t *testing.T) {
+	t.Parallel()
+
+	for _, mode := range []config.Deduplication{config.DeduplicationFirst, config.DeduplicationLast} {
+		cfg := config.Default()
+		cfg.Deduplication = mode
+		cfg.SpillBufferSize = 512 // force spilling and repeated partitioning
+		cfg.TempDir = t.TempDir()
+
+		out := &rowsCollector{}
+		d, err := New(cfg, out, []string{"id", "value"}, []string{"id"}, ',', '"', 0)
+		require.NoError(t, err)
+
+		// 100 unique keys, each 3 times
+		for i := 0; i < 3; i++ {
+			for id := 0; id < 100; id++ {
+				require.NoError(t, d.Write([]byte(fmt.Sprintf("\"%d\",\"%d\"\n", id, i))))
+			}
+		}
+		require.NoError(t, d.Close())
+
+		// Order is not preserved between partitions
+		var expected []string
+		for id := 0; id < 100; id++ {
+			if mode == config.DeduplicationFirst {
+				expected = append(expected, fmt.Sprintf("\"%d\",\"0\"\n", id))
+			} else {
+				expected = append(expected, fmt.Sprintf("\"%d\",\"2\"\n", id))
+			}
+		}
+		sort.Strings(expected)
+		sort.Strings(out.rows)
+
+		assert.Equal(t, expected, out.rows, mode.String())
+		assert.Equal(t, uint64(200), d.Duplicates())
+		assertNoSpillFiles(t, cfg.TempDir)
+	}
+}
+
+func TestDeduplicator_Spilled_LastRowWithoutNewLine(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.Deduplication = config.DeduplicationFirst
+	cfg.SpillBufferSize = 512 // force spilling
+	cfg.TempDir = t.TempDir()
+
+	out := &rowsCollector{}
+	d, err := New(cfg, out, []string{"id", "value"}, []string{"id"}, ',', '"', 0)
+	require.NoError(t, err)
+	for id := 0; id < 100; id++ {
+		require.NoError(t, d.Write([]byte(fmt.Sprintf("\"%d\",\"a\"\n", id))))
+	}
+	require.NoError(t, d.Write([]byte(`"last","b"`))) // last row without new line
+	require.NoError(t, d.Close())
+
+	// Each row ends with a new line, so the rows are not merged, if the order is changed
+	assert.Len(t, out.rows, 101)
+	assert.Contains(t, out.rows, "\"last\",\"b\"\n")
+	for _, row := range out.rows {
+		assert.Equal(t, 1, strings.Count(row, "\n"), row)
+	}
+	assertNoSpillFiles(t, cfg.TempDir)
+}
+
+func TestDeduplicator_Abort(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.Deduplication = config.DeduplicationFirst
+	cfg.SpillBufferSize = 512 // force spilling
+	cfg.TempDir = t.TempDir()
+
+	d, err := New(cfg, &rowsCollector{}, []string{"id", "value"}, []string{"id"}, ',', '"', 0)
+	require.NoError(t, err)
+	for id := 0; id < 100; id++ {
+		require.NoError(t, d.Write([]byte(fmt.Sprintf("\"%d\",\"a\"\n", id))))
+	}
+
+	// The slicing failed, the Close is not called
+	entries, err := os.ReadDir(cfg.TempDir)
+	require.NoError(t, err)
+	assert.NotEmpty(t, entries)
+	require.NoError(t, d.Abort())
+	assertNoSpillFiles(t, cfg.TempDir)
+}
+
+func TestDeduplicator_Close_Error(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.Deduplication = config.DeduplicationFirst
+	cfg.SpillBufferSize = 512 // force spilling and repeated partitioning
+	cfg.TempDir = t.TempDir()
+
+	// The next stage fails, in the middle of the partitions
+	out := &failingWriter{failAt: 10}
+	d, err := New(cfg, out, []string{"id", "value"}, []string{"id"}, ',', '"', 0)
+	require.NoError(t, err)
+	for id := 0; id < 100; id++ {
+		require.NoError(t, d.Write([]byte(fmt.Sprintf("\"%d\",\"a\"\n", id))))
+	}
+	err = d.Close()
+	if assert.Error(t, err) {
+		assert.Equal(t, "some error", err.Error())
+	}
+	assertNoSpillFiles(t, cfg.TempDir)
+}
+
+func TestDeduplicator_PartitionsCount(t *testing.T) {
+	t.Parallel()
+
+	d := &Deduplicator{maxMemory: 32 * datasize.MB}
+	assert.Equal(t, minPartitions, d.partitionsCount(0))
+	assert.Equal(t, 10, d.partitionsCount(320*datasize.MB))
+
+	// The fan-out is limited, larger partitions are partitioned again
+	assert.Equal(t, maxPartitions, d.partitionsCount(100*datasize.GB))
+
+	// Write buffers of the partitions must fit into a part of the memory
+	d.maxMemory = 4 * datasize.MB
+	assert.Equal(t, 16, d.partitionsCount(100*datasize.GB))
+}
+
+func TestSet_SizeLast(t *testing.T) {
+	t.Parallel()
+
+	s := newSet(config.DeduplicationLast)
+	s.Add("1", []byte("foo"))
+	s.Add("1", []byte("bar"))
+	s.Add("1", []byte("baz"))
+
+	// Replaced entries remain in the slice, so their overhead is counted
+	assert.Equal(t, datasize.ByteSize(3*entryOverhead+len("1")+len("baz")), s.Size())
+}
+
+func TestDeduplicator_MissingPrimaryKey(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.Deduplication = config.DeduplicationFirst
+
+	_, err := New(cfg, &rowsCollector{}, []string{"id"}, nil, ',', '"', 0)
+	if assert.Error(t, err) {
+		assert.Equal(t, `deduplication requires the manifest "primary_key"`, err.Error())
+	}
+
+	_, err = New(cfg, &rowsCollector{}, []string{"id"}, []string{"foo"}, ',', '"', 0)
+	if assert.Error(t, err) {
+		assert.Equal(t, `invalid primary key: column "foo" not found in the table columns`, err.Error())
+	}
+}
+
+func assertNoSpillFiles(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,57 @@
+package dedup
+
+import (
+	"github.com/c2h5oh/datasize"
+
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+)
+
+// set is an in-memory deduplication of rows by a key, the order of the rows is preserved.
+type set struct {
+	mode    config.Deduplication
+	index   map[string]int // key -> index of the entry
+	entries []entry
+	size    datasize.ByteSize
+}
+
+type entry struct {
+	key string
+	row []byte // nil if the row has been replaced by a later occurrence
+}
+
+func newSet(mode config.Deduplication) *set {
+	return &set{mode: mode, index: make(map[string]int)}
+}
+
+// Add the row to the set, the row must not be modified later.
+func (s *set) Add(key string, row []byte) {
+	if i, found := s.index[key]; found {
+		if s.mode == config.DeduplicationFirst {
+			// Keep the first occurrence
+			return
+		}
+
+		// Keep the last occurrence, at the position of the last occurrence.
+		// The previous entry remains in the slice, only the key and the row are released.
+		s.size -= datasize.ByteSize(len(s.entries[i].key) + len(s.entries[i].row))
+		s.entries[i].key = ""
+		s.entries[i].row = nil
+	}
+
+	// Each entry is counted, including the replaced entries in the "last" mode
+	s.size += datasize.ByteSize(len(key) + entryOverhead)
+	s.index[key] = len(s.entries)
+	s.entries = append(s.entries, entry{key: key, row: row})
+	s.size += datasize.ByteSize(len(row))
+}
+
+// Size returns estimated memory usage.
+func (s *set) Size() datasize.ByteSize {
+	return s.size
+}
+
+func (s *set) Reset() {
+	s.index = make(map[string]int)
+	s.entries = nil
+	s.size = 0
+}
@@ -0,0 +1,58 @@
+package slicer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/c2h5oh/datasize"
+
+	"github.com/keboola/processor-split-table/internal/pkg/diskspace"
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/log"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/utils"
+)
+
+// gzipRatio is a heuristic compression ratio of a CSV table by gzip.
+const gzipRatio = 5
+
+// estimateOutputSize estimates size of the output table from the input size.
+// A gzipped input is decompressed and a gzipped output is compressed by the gzipRatio, otherwise the ratio is 1:1.
+func estimateOutputSize(cfg config.Config, inputPaths []string, inputSize datasize.ByteSize) datasize.ByteSize {
+	size := inputSize
+	for _, path := range inputPaths {
+		if strings.HasSuffix(path, kbc.GzipFileExtension) {
+			size *= gzipRatio
+			break
+		}
+	}
+	if cfg.Gzip && cfg.OutputFormat != config.FormatParquet && cfg.OutputFormat != config.FormatArrow {
+		size /= gzipRatio
+	}
+	return size
+}
+
+// checkDiskSpace compares the output size with the free space of the output filesystem, according to the DiskSpaceCheck policy.
+// The check is skipped, if the output size or the free space is unknown.
+func checkDiskSpace(logger log.Logger, table Table, outputSize datasize.ByteSize) error {
+	if table.DiskSpaceCheck == config.DiskSpaceCheckNone || outputSize == 0 {
+		return nil
+	}
+
+	free, ok, err := diskspace.Free(table.OutPath)
+	if err != nil {
+		return err
+	} else if !ok || outputSize <= free {
+		return nil
+	}
+
+	msg := fmt.Sprintf(
+		`disk space for the output table "%s" may not be enough: estimated size %s, free space %s on "%s"`,
+		table.Name, utils.RemoveSpaces(outputSize.HumanReadable()), utils.RemoveSpaces(free.HumanReadable()), table.OutPath,
+	)
+	if table.DiskSpaceCheck == config.DiskSpaceCheckFail {
+		return kbc.UserErrorf("%s", msg)
+	}
+	logger.Warnf("The %s.", msg)
+	return nil
+}
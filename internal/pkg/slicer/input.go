@@ -0,0 +1,53 @@
+package slicer
+
+import (
+	"errors"
+	"os"
+
+	"github.com/c2h5oh/datasize"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	manifestPkg "github.com/keboola/processor-split-table/internal/pkg/manifest"
+)
+
+// input is an existing input table, used by the merge, inspect and verify commands.
+type input struct {
+	sliced   bool
+	slices   kbc.Slices
+	size     datasize.ByteSize
+	manifest *manifestPkg.Manifest
+}
+
+// openInput loads the manifest and finds the slices of the input table, the manifest must exist, if the path is specified.
+func openInput(inPath, inManifestPath string) (*input, error) {
+	// Get input type
+	stat, err := os.Stat(inPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, kbc.UserErrorf(`input table "%s" not found`, inPath)
+	} else if err != nil {
+		return nil, err
+	}
+	in := &input{sliced: stat.IsDir()}
+
+	// Load manifest
+	if in.manifest, err = manifestPkg.LoadManifest(inManifestPath); err != nil {
+		return nil, err
+	}
+	if inManifestPath != "" && !in.manifest.Exists() {
+		return nil, kbc.UserErrorf(`manifest "%s" not found`, inManifestPath)
+	}
+
+	// Define inputs and input size
+	if in.sliced {
+		if in.slices, err = kbc.FindSlices(inPath); err != nil {
+			return nil, err
+		}
+		if in.size, err = in.slices.Size(); err != nil {
+			return nil, err
+		}
+	} else {
+		in.size = datasize.ByteSize(stat.Size())
+	}
+
+	return in, nil
+}
@@ -0,0 +1,193 @@
+package slicer
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/benbjohnson/clock"
+	"github.com/c2h5oh/datasize"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/log"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/rowsreader/progress"
+)
+
+// delimiterCandidates are compared when the delimiter is detected from the first row.
+const delimiterCandidates = ",;\t|"
+
+const (
+	ColumnsFromManifest = "manifest" // the manifest "columns" field
+	ColumnsFromHeader   = "header"   // the first row of the CSV file
+	ColumnsFromSchema   = "schema"   // the schema or the keys of a non-CSV format
+)
+
+// InspectedTable defines the input table of the inspect and verify commands.
+// The read-ahead, the input format and the log interval options of the Config are used, other options are ignored.
+type InspectedTable struct {
+	config.Config  `json:"config" mapstructure:",squash"`
+	Name           string `json:"name" mapstructure:"table-name"` // optional, the base name of the input path by default
+	InPath         string `validate:"required" json:"inPath" mapstructure:"table-input-path"`
+	InManifestPath string `json:"inManifestPath" mapstructure:"table-input-manifest-path"`
+}
+
+// TableInfo is the result of the InspectTable function.
+type TableInfo struct {
+	Name    string            `json:"name"`
+	Path    string            `json:"path"`
+	Sliced  bool              `json:"sliced"`
+	Format  config.Format     `json:"format"`
+	Size    datasize.ByteSize `json:"size"`
+	Slices  []SliceInfo       `json:"slices"`
+	Dialect Dialect           `json:"dialect"`
+	// Columns are empty, if the input is a sliced CSV without the manifest columns.
+	Columns       []string `json:"columns"`
+	ColumnsSource string   `json:"columnsSource,omitempty"`
+}
+
+// SliceInfo describes one slice of the input table.
+type SliceInfo struct {
+	Name        string            `json:"name"`
+	Size        datasize.ByteSize `json:"size"`
+	Compression string            `json:"compression"` // "gzip" or "none"
+}
+
+// Dialect of the CSV input.
+// The Delimiter and the Enclosure are used for reading, they are defined by the manifest or by the defaults.
+// The DetectedDelimiter is the most frequent candidate in the first row, it is empty if there is none.
+type Dialect struct {
+	Delimiter         string `json:"delimiter"`
+	Enclosure         string `json:"enclosure"`
+	DetectedDelimiter string `json:"detectedDelimiter,omitempty"`
+}
+
+// InspectTable describes the input table without reading all rows, only the first row of a CSV table is read.
+func InspectTable(logger log.Logger, table InspectedTable) (*TableInfo, error) {
+	// Validate
+	table.setDefaultName()
+	logger = log.With(logger, "table", table.Name)
+	val := validator.New()
+	if err := val.Struct(table); err != nil {
+		return nil, kbc.UserErrorf(`table definition is not valid: %w`, err)
+	}
+
+	// Open input
+	in, err := openInput(table.InPath, table.InManifestPath)
+	if err != nil {
+		return nil, err
+	}
+	manifest := in.manifest
+
+	info := &TableInfo{
+		Name:   table.Name,
+		Path:   table.InPath,
+		Sliced: in.sliced,
+		Size:   in.size,
+		Dialect: Dialect{
+			Delimiter: string(manifest.Delimiter()),
+			Enclosure: string(manifest.Enclosure()),
+		},
+	}
+
+	// Describe slices
+	if in.sliced {
+		for _, slice := range in.slices {
+			stat, err := slice.Info()
+			if err != nil {
+				return nil, err
+			}
+			info.Slices = append(info.Slices, newSliceInfo(slice.Name(), datasize.ByteSize(stat.Size())))
+		}
+	} else {
+		info.Slices = append(info.Slices, newSliceInfo(filepath.Base(table.InPath), in.size))
+	}
+
+	// Create reader
+	progressLogger := progress.NewLogger(clock.New(), logger, table.LogInterval, in.size, fmt.Sprintf("Inspecting table \"%s\"", table.Name))
+	defer progressLogger.Close()
+	reader, err := newReader(context.Background(), progressLogger, table.Config, table.InPath, in.sliced, in.slices, manifest)
+	if err != nil {
+		return nil, err
+	}
+	info.Format = reader.Format()
+
+	// Get columns and the first row
+	var firstRow []byte
+	switch {
+	case info.Format != config.FormatCSV:
+		info.Columns, _ = reader.Header()
+		info.ColumnsSource = ColumnsFromSchema
+	case manifest.HasColumns():
+		info.Columns = manifest.Columns()
+		info.ColumnsSource = ColumnsFromManifest
+		if reader.Read() {
+			firstRow = reader.Bytes()
+		}
+	case !in.sliced:
+		if info.Columns, err = reader.Header(); err != nil {
+			_ = reader.Close()
+			return nil, err
+		}
+		info.ColumnsSource = ColumnsFromHeader
+		firstRow = reader.Bytes() // the header row
+	default:
+		if reader.Read() {
+			firstRow = reader.Bytes()
+		}
+	}
+
+	// Detect delimiter
+	if delimiter := detectDelimiter(firstRow, manifest.Enclosure()); delimiter != 0 {
+		info.Dialect.DetectedDelimiter = string(delimiter)
+		if delimiter != manifest.Delimiter() {
+			logger.Warnf(`The detected delimiter "%s" of table "%s" differs from the manifest delimiter "%s".`, string(delimiter), table.Name, string(manifest.Delimiter()))
+		}
+	}
+
+	// Remaining rows are not read
+	if err := reader.Close(); err != nil {
+		return nil, fmt.Errorf("error when reading CSV \"%s\": %w", table.InPath, err)
+	}
+
+	return info, nil
+}
+
+func (t *InspectedTable) setDefaultName() {
+	if t.Name == "" && t.InPath != "" {
+		t.Name = filepath.Base(t.InPath)
+	}
+}
+
+func newSliceInfo(name string, size datasize.ByteSize) SliceInfo {
+	compression := "none"
+	if strings.HasSuffix(name, kbc.GzipFileExtension) {
+		compression = "gzip"
+	}
+	return SliceInfo{Name: name, Size: size, Compression: compression}
+}
+
+// detectDelimiter returns the most frequent delimiter candidate outside the enclosure, or 0 if there is none.
+func detectDelimiter(row []byte, enclosure byte) byte {
+	var counts [256]int
+	insideEnclosure := false
+	for _, char := range row {
+		if char == enclosure {
+			// An escaped enclosure inverts the state twice
+			insideEnclosure = !insideEnclosure
+		} else if !insideEnclosure {
+			counts[char]++
+		}
+	}
+
+	var delimiter byte
+	bestCount := 0
+	for i := 0; i < len(delimiterCandidates); i++ {
+		if candidate := delimiterCandidates[i]; counts[candidate] > bestCount {
+			delimiter, bestCount = candidate, counts[candidate]
+		}
+	}
+	return delimiter
+}
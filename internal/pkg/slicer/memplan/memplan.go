@@ -0,0 +1,293 @@
+// Package memplan plans sizes of the buffer pools of a table, so the slicing fits into the memory limit.
+//
+// The estimated memory usage of a table is a sum of:
+//   - the scanner buffer, it grows from the rowsreader.StartTokenBufferSize up to the rowsreader.MaxTokenBufferSize,
+//     the maximum is reserved, because it is the maximum length of a row,
+//   - the read-ahead buffers and the gzip reader of each input slice opened ahead,
+//   - the gzip writer blocks, or the output buffer, if gzip is disabled,
+//   - the buffered record of the "parquet" and "arrow" output, see slicedwriter.MaxRecordSize,
+//   - the spill buffer of each stage using it: the deduplication, the sort and the sample reservoir,
+//   - the statistics of each column, if the profile is enabled, see profile.ColumnSize.
+//
+// Settings not set by the user are planned, they are reduced until the estimation fits into the limit.
+// Explicit settings, see config.Config.Explicit, are never changed, so an error is returned, if they don't fit.
+package memplan
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/c2h5oh/datasize"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/profile"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/rowsreader"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/slicedwriter"
+	"github.com/keboola/processor-split-table/internal/pkg/utils"
+)
+
+const (
+	// minBlockSize is the minimum size of the planned buffers, it is also the minimum allowed by the validation.
+	minBlockSize = 32 * datasize.KB
+	// gzipReaderSize is the memory of one pgzip reader, 4 blocks of 1MB by default.
+	gzipReaderSize = 4 * datasize.MB
+	// gzipWriterFactor of the block size, each block has an input and an output buffer.
+	gzipWriterFactor = 2
+)
+
+// Plan is a combination of the pool sizes, see Apply.
+type Plan struct {
+	// Limit is the memory available for the table, math.MaxInt64 means no limit.
+	Limit datasize.ByteSize
+	// Changed is true, if at least one setting was reduced to fit into the Limit.
+	Changed bool
+
+	GzipConcurrency uint32
+	GzipBlockSize   datasize.ByteSize
+	BufferSize      datasize.ByteSize
+	AheadBlocks     uint32
+	AheadBlockSize  datasize.ByteSize
+	SpillBufferSize datasize.ByteSize
+
+	gzip        bool
+	aheadSlices uint32
+	record      datasize.ByteSize
+	spillStages int
+	profiled    int
+}
+
+// part of the estimation, reduce halves one of the planned settings, it returns false, if nothing can be reduced.
+type part struct {
+	size   datasize.ByteSize
+	reduce func() bool
+}
+
+// New plans the pool sizes for the configuration, the number of profiled columns, the memory limit and the number of CPU threads.
+// The profiled columns are 0, if the profile is disabled or the columns are not known yet, see SetProfiledColumns.
+// A kbc.UserError is returned, if the explicit settings exceed the memory limit.
+func New(cfg config.Config, profiled int, limit datasize.ByteSize, cpus int) (Plan, error) {
+	explicit := cfg.Explicit
+	p := Plan{
+		Limit:           limit,
+		GzipConcurrency: cfg.GzipConcurrency,
+		GzipBlockSize:   cfg.GzipBlockSize,
+		BufferSize:      cfg.BufferSize,
+		AheadBlocks:     cfg.AheadBlocks,
+		AheadBlockSize:  cfg.AheadBlockSize,
+		SpillBufferSize: cfg.SpillBufferSize,
+		gzip:            cfg.Gzip && cfg.OutputFormat != config.FormatParquet && cfg.OutputFormat != config.FormatArrow,
+		aheadSlices:     cfg.AheadSlices,
+		record:          recordSize(cfg),
+		spillStages:     spillStages(cfg),
+		profiled:        profiled,
+	}
+
+	// The auto gzip concurrency is the number of CPU threads
+	autoConcurrency := cfg.GzipConcurrency == 0
+	if autoConcurrency {
+		p.GzipConcurrency = uint32(max(cpus, 1))
+	}
+	planConcurrency := autoConcurrency || !explicit.GzipConcurrency
+
+	// Each step halves one planned setting, the biggest part of the estimation is reduced first
+	for p.Total() > limit {
+		var parts []part
+		add := func(size datasize.ByteSize, reduce func() bool) {
+			parts = append(parts, part{size: size, reduce: reduce})
+		}
+		if p.gzip {
+			add(p.Writer(), func() bool {
+				return (planConcurrency && halveCount(&p.GzipConcurrency)) ||
+					(!explicit.GzipBlockSize && halveSize(&p.GzipBlockSize))
+			})
+		} else {
+			add(p.Writer(), func() bool {
+				return !explicit.BufferSize && halveSize(&p.BufferSize)
+			})
+		}
+		add(p.ReadAhead(), func() bool {
+			return (!explicit.AheadBlocks && halveCount(&p.AheadBlocks)) ||
+				(!explicit.AheadBlockSize && halveSize(&p.AheadBlockSize))
+		})
+		if p.spillStages > 0 {
+			add(p.Spill(), func() bool {
+				return !explicit.SpillBufferSize && halveSize(&p.SpillBufferSize)
+			})
+		}
+
+		reduced := false
+		for !reduced && len(parts) > 0 {
+			biggest := 0
+			for i := range parts {
+				if parts[i].size > parts[biggest].size {
+					biggest = i
+				}
+			}
+			reduced = parts[biggest].reduce()
+			parts = append(parts[:biggest], parts[biggest+1:]...)
+		}
+		if !reduced {
+			return Plan{}, p.limitError()
+		}
+		p.Changed = true
+	}
+
+	return p, nil
+}
+
+// SetProfiledColumns sets the number of profiled columns, if they were not known by New, for example, they are read from the header.
+// The pool sizes are already used, so they are not reduced, a kbc.UserError is returned, if the estimation exceeds the limit.
+func (p *Plan) SetProfiledColumns(profiled int) error {
+	p.profiled = profiled
+	if p.Total() > p.Limit {
+		return p.limitError()
+	}
+	return nil
+}
+
+// Apply sets the planned pool sizes to the configuration.
+func (p Plan) Apply(cfg *config.Config) {
+	cfg.GzipConcurrency = p.GzipConcurrency
+	cfg.GzipBlockSize = p.GzipBlockSize
+	cfg.BufferSize = p.BufferSize
+	cfg.AheadBlocks = p.AheadBlocks
+	cfg.AheadBlockSize = p.AheadBlockSize
+	cfg.SpillBufferSize = p.SpillBufferSize
+}
+
+// Scanner returns the reserved size of the scanner buffer.
+func (p Plan) Scanner() datasize.ByteSize {
+	return datasize.ByteSize(rowsreader.MaxTokenBufferSize)
+}
+
+// ReadAhead returns the estimated size of the read-ahead buffers and the gzip readers.
+// One slice is read and AheadSlices are opened ahead.
+func (p Plan) ReadAhead() datasize.ByteSize {
+	slices := datasize.ByteSize(p.aheadSlices + 1)
+	return slices * (datasize.ByteSize(p.AheadBlocks)*p.AheadBlockSize + gzipReaderSize)
+}
+
+// Writer returns the estimated size of the gzip writer blocks, or the output buffer, if gzip is disabled.
+func (p Plan) Writer() datasize.ByteSize {
+	if p.gzip {
+		return gzipWriterFactor * datasize.ByteSize(p.GzipConcurrency) * p.GzipBlockSize
+	}
+	return p.BufferSize
+}
+
+// Record returns the maximum size of the buffered record of the "parquet" and "arrow" output, 0 for other formats.
+func (p Plan) Record() datasize.ByteSize {
+	return p.record
+}
+
+// Spill returns the size of the spill buffers, each stage using it can hold the SpillBufferSize.
+func (p Plan) Spill() datasize.ByteSize {
+	return datasize.ByteSize(p.spillStages) * p.SpillBufferSize
+}
+
+// Profile returns the estimated size of the statistics of the profiled columns.
+func (p Plan) Profile() datasize.ByteSize {
+	return datasize.ByteSize(p.profiled) * profile.ColumnSize
+}
+
+// Total returns the estimated memory usage of the table.
+func (p Plan) Total() datasize.ByteSize {
+	return p.Scanner() + p.ReadAhead() + p.Writer() + p.Record() + p.Spill() + p.Profile()
+}
+
+// String returns a human-readable description of the plan, for logging.
+func (p Plan) String() string {
+	var b strings.Builder
+	if p.gzip {
+		_, _ = fmt.Fprintf(&b, "gzip concurrency %d, gzip block %s", p.GzipConcurrency, formatSize(p.GzipBlockSize))
+	} else {
+		_, _ = fmt.Fprintf(&b, "buffer %s", formatSize(p.BufferSize))
+	}
+	_, _ = fmt.Fprintf(&b, ", ahead blocks %d × %s", p.AheadBlocks, formatSize(p.AheadBlockSize))
+	if p.spillStages > 0 {
+		_, _ = fmt.Fprintf(&b, ", spill buffer %s", formatSize(p.SpillBufferSize))
+	}
+	_, _ = fmt.Fprintf(&b, ", estimated %s of %s: %s", formatSize(p.Total()), formatSize(p.Limit), p.usage())
+	return b.String()
+}
+
+// usage lists the parts of the estimation.
+func (p Plan) usage() string {
+	parts := []string{
+		"scanner " + formatSize(p.Scanner()),
+		"read-ahead " + formatSize(p.ReadAhead()),
+	}
+	if p.gzip {
+		parts = append(parts, "gzip "+formatSize(p.Writer()))
+	} else {
+		parts = append(parts, "buffer "+formatSize(p.Writer()))
+	}
+	if p.record > 0 {
+		parts = append(parts, "record "+formatSize(p.Record()))
+	}
+	if p.spillStages > 0 {
+		parts = append(parts, "spill "+formatSize(p.Spill()))
+	}
+	if p.profiled > 0 {
+		parts = append(parts, "profile "+formatSize(p.Profile()))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (p Plan) limitError() error {
+	return kbc.UserErrorf(
+		`memory limit %s is too low for the settings, at least %s is required: %s`,
+		formatSize(p.Limit), formatSize(p.Total()), p.usage(),
+	)
+}
+
+// recordSize returns the maximum size of the buffered record, it is limited also by the slice size.
+func recordSize(cfg config.Config) datasize.ByteSize {
+	if cfg.OutputFormat != config.FormatParquet && cfg.OutputFormat != config.FormatArrow {
+		return 0
+	}
+	if cfg.Mode == config.ModeBytes {
+		return min(slicedwriter.MaxRecordSize, cfg.BytesPerSlice)
+	}
+	return slicedwriter.MaxRecordSize
+}
+
+// spillStages returns the number of the stages holding up to the SpillBufferSize in memory.
+func spillStages(cfg config.Config) int {
+	stages := 0
+	if cfg.Deduplication != config.DeduplicationNone {
+		stages++
+	}
+	if len(cfg.SortBy) > 0 {
+		stages++
+	}
+	if cfg.Sample.Rows > 0 {
+		stages++
+	}
+	return stages
+}
+
+func halveCount(v *uint32) bool {
+	if *v <= 1 {
+		return false
+	}
+	*v /= 2
+	return true
+}
+
+func halveSize(v *datasize.ByteSize) bool {
+	if *v/2 < minBlockSize {
+		return false
+	}
+	*v /= 2
+	return true
+}
+
+func formatSize(v datasize.ByteSize) string {
+	if v >= math.MaxInt64 {
+		return "unlimited"
+	}
+	return utils.RemoveSpaces(v.HumanReadable())
+}
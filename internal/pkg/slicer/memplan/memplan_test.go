@@ -0,0 +1,134 @@
+package memplan
+
+import (
+	"math"
+	"testing"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+)
+
+func TestNew_Unlimited(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	plan, err := New(cfg, 0, math.MaxInt64, 8)
+	require.NoError(t, err)
+
+	// Defaults are kept, the auto gzip concurrency is the number of CPU threads
+	assert.False(t, plan.Changed)
+	assert.Equal(t, uint32(8), plan.GzipConcurrency)
+	assert.Equal(t, cfg.GzipBlockSize, plan.GzipBlockSize)
+	assert.Equal(t, cfg.AheadBlocks, plan.AheadBlocks)
+	assert.Equal(t, cfg.AheadBlockSize, plan.AheadBlockSize)
+
+	// 50MB scanner + 2 slices * (16 * 1MB read-ahead + 4MB gzip reader) + 8 * 1MB * 2 gzip writer
+	assert.Equal(t, 106*datasize.MB, plan.Total())
+	assert.Equal(t, "gzip concurrency 8, gzip block 1024.0KB, ahead blocks 16 × 1024.0KB, estimated 106.0MB of unlimited: scanner 50.0MB, read-ahead 40.0MB, gzip 16.0MB", plan.String())
+}
+
+func TestNew_Reduced(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.SortBy = []string{"id"}
+	plan, err := New(cfg, 0, 112*datasize.MB, 32)
+	require.NoError(t, err)
+
+	// The biggest parts are reduced first
+	assert.True(t, plan.Changed)
+	assert.LessOrEqual(t, plan.Total(), 112*datasize.MB)
+	assert.Equal(t, uint32(8), plan.GzipConcurrency)
+	assert.Equal(t, uint32(8), plan.AheadBlocks)
+	assert.Equal(t, cfg.SpillBufferSize/2, plan.SpillBufferSize)
+
+	// Apply
+	plan.Apply(&cfg)
+	assert.Equal(t, uint32(8), cfg.GzipConcurrency)
+	assert.Equal(t, uint32(8), cfg.AheadBlocks)
+	assert.Equal(t, 16*datasize.MB, cfg.SpillBufferSize)
+}
+
+func TestNew_ExplicitSettings(t *testing.T) {
+	t.Parallel()
+
+	// Explicit settings are not changed
+	cfg := config.Default()
+	cfg.GzipConcurrency = 16
+	cfg.GzipBlockSize = 4 * datasize.MB
+	cfg.Explicit.GzipConcurrency = true
+	cfg.Explicit.GzipBlockSize = true
+	_, err := New(cfg, 0, 64*datasize.MB, 32)
+	require.Error(t, err)
+	assert.IsType(t, &kbc.UserError{}, err)
+	assert.Equal(t, "memory limit 64.0MB is too low for the settings, at least 186.1MB is required: scanner 50.0MB, read-ahead 8.1MB, gzip 128.0MB", err.Error())
+
+	// Only the output buffer is used, if gzip is disabled
+	cfg.Gzip = false
+	cfg.BufferSize = 10 * datasize.MB
+	cfg.Explicit.BufferSize = true
+	plan, err := New(cfg, 0, 80*datasize.MB, 32)
+	require.NoError(t, err)
+	assert.Equal(t, 10*datasize.MB, plan.BufferSize)
+	assert.Equal(t, 10*datasize.MB, plan.Writer())
+}
+
+func TestNew_ExplicitDefaultValue(t *testing.T) {
+	t.Parallel()
+
+	// An explicit setting is not changed, even if it has the default value
+	cfg := config.Default()
+	cfg.Explicit.AheadBlocks = true
+	cfg.Explicit.AheadBlockSize = true
+	plan, err := New(cfg, 0, 100*datasize.MB, 8)
+	require.NoError(t, err)
+	assert.True(t, plan.Changed)
+	assert.Equal(t, cfg.AheadBlocks, plan.AheadBlocks)
+	assert.Equal(t, cfg.AheadBlockSize, plan.AheadBlockSize)
+	assert.Equal(t, uint32(4), plan.GzipConcurrency)
+}
+
+func TestNew_AllConsumers(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.OutputFormat = config.FormatParquet
+	cfg.Deduplication = config.DeduplicationFirst
+	cfg.SortBy = []string{"id"}
+	cfg.Sample.Rows = 100
+	plan, err := New(cfg, 64, math.MaxInt64, 8)
+	require.NoError(t, err)
+
+	// 50MB scanner + 40MB read-ahead + 20MB buffer + 64MB record + 3 * 32MB spill + 64 * 16KB profile
+	assert.Equal(t, 64*datasize.MB, plan.Record())
+	assert.Equal(t, 96*datasize.MB, plan.Spill())
+	assert.Equal(t, 1*datasize.MB, plan.Profile())
+	assert.Equal(t, 271*datasize.MB, plan.Total())
+	assert.Equal(t, "buffer 20.0MB, ahead blocks 16 × 1024.0KB, spill buffer 32.0MB, estimated 271.0MB of unlimited: scanner 50.0MB, read-ahead 40.0MB, buffer 20.0MB, record 64.0MB, spill 96.0MB, profile 1024.0KB", plan.String())
+
+	// The record is limited by the slice size
+	cfg.BytesPerSlice = 10 * datasize.MB
+	plan, err = New(cfg, 64, math.MaxInt64, 8)
+	require.NoError(t, err)
+	assert.Equal(t, 10*datasize.MB, plan.Record())
+}
+
+func TestPlan_SetProfiledColumns(t *testing.T) {
+	t.Parallel()
+
+	// The columns are read from the header, after the pools are planned
+	plan, err := New(config.Default(), 0, 128*datasize.MB, 8)
+	require.NoError(t, err)
+	require.NoError(t, plan.SetProfiledColumns(100))
+	assert.Equal(t, 1600*datasize.KB, plan.Profile())
+
+	// The pools are not reduced anymore
+	err = plan.SetProfiledColumns(10_000)
+	require.Error(t, err)
+	assert.IsType(t, &kbc.UserError{}, err)
+	assert.Equal(t, "memory limit 128.0MB is too low for the settings, at least 262.2MB is required: scanner 50.0MB, read-ahead 40.0MB, gzip 16.0MB, profile 156.2MB", err.Error())
+}
@@ -0,0 +1,170 @@
+package slicer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/benbjohnson/clock"
+	"github.com/c2h5oh/datasize"
+	"github.com/dustin/go-humanize"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/log"
+	"github.com/keboola/processor-split-table/internal/pkg/pool"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/closer"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/rowsreader/progress"
+	"github.com/keboola/processor-split-table/internal/pkg/utils"
+)
+
+// MergedTable defines the reverse operation to the slicing, slices of the input table are merged to one CSV file.
+// The read-ahead, the gzip and the log interval options of the Config are used, other options are ignored.
+type MergedTable struct {
+	config.Config  `json:"config" mapstructure:",squash"`
+	Name           string `validate:"required" json:"name" mapstructure:"table-name"`
+	InPath         string `validate:"required" json:"inPath" mapstructure:"table-input-path"`
+	InManifestPath string `json:"inManifestPath" mapstructure:"table-input-manifest-path"`
+	OutPath        string `validate:"required" json:"outPath" mapstructure:"table-output-path"`
+	// Header enables writing of the manifest columns as the first row.
+	Header bool `json:"header" mapstructure:"header"`
+}
+
+// MergeTable writes all rows of the sliced table to the one CSV file, optionally compressed by gzip.
+func MergeTable(logger log.Logger, table MergedTable) (err error) {
+	logger = log.With(logger, "table", table.Name)
+
+	// Validate
+	val := validator.New()
+	if err := val.Struct(table); err != nil {
+		return kbc.UserErrorf(`table definition is not valid: %w`, err)
+	}
+
+	// Open input
+	in, err := openInput(table.InPath, table.InManifestPath)
+	if err != nil {
+		return err
+	}
+	manifest := in.manifest
+
+	// Create progress logger
+	progressMessage := fmt.Sprintf("Merging table \"%s\"", table.Name)
+	progressLogger := progress.NewLogger(clock.New(), logger, table.LogInterval, in.size, progressMessage)
+	defer progressLogger.Close()
+	logger.Info(progressMessage + ".") // log initial message
+
+	// Create reader
+	reader, err := newReader(context.Background(), progressLogger, table.Config, table.InPath, in.sliced, in.slices, manifest)
+	if err != nil {
+		return err
+	}
+
+	// Get header, columns of a non-CSV input are always taken from the input.
+	// A CSV file without the manifest columns starts with the header, so it is read and written back.
+	var header []string
+	if table.Header {
+		if manifest.HasColumns() && reader.Format() == config.FormatCSV {
+			header = manifest.Columns()
+		} else if header, err = reader.Header(); err != nil {
+			return err
+		}
+	}
+
+	// Create writer
+	out, closers, err := createMergedFile(table)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := closers.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("cannot close file \"%s\": %w", table.OutPath, closeErr)
+		}
+	}()
+
+	// Write header
+	var bytes datasize.ByteSize
+	if header != nil {
+		row := headerRow(header, manifest.Delimiter(), manifest.Enclosure())
+		if _, err := out.Write(row); err != nil {
+			return err
+		}
+		bytes += datasize.ByteSize(len(row))
+	}
+
+	// Copy all rows
+	var rows uint64
+	for reader.Read() {
+		row := reader.Bytes()
+		if _, err := out.Write(row); err != nil {
+			return err
+		}
+		rows++
+		bytes += datasize.ByteSize(len(row))
+	}
+
+	// Close the reader
+	if err = reader.Close(); err != nil {
+		return fmt.Errorf("error when reading CSV \"%s\": %w", table.InPath, err)
+	}
+
+	// Log statistics
+	logger.Infof(
+		"Table \"%s\" merged: in/out: %d / 1 slices, %s / %s bytes, %s rows.",
+		table.Name,
+		reader.Slices(),
+		utils.RemoveSpaces(in.size.HumanReadable()),
+		utils.RemoveSpaces(bytes.HumanReadable()),
+		humanize.Comma(int64(rows)),
+	)
+	return nil
+}
+
+// createMergedFile opens the output file, the closers must be called to flush the buffers.
+func createMergedFile(table MergedTable) (io.Writer, closer.Closers, error) {
+	var closers closer.Closers
+
+	// Open the file for writing
+	file, err := os.OpenFile(table.OutPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, kbc.NewFilePermissions)
+	if err != nil {
+		return nil, nil, err
+	}
+	closers.Append(file.Close)
+
+	// Add gzip compression
+	if table.Gzip {
+		gzipWriters := pool.GZIPWriters(table.GzipLevel, table.GzipBlockSize, int(table.GzipConcurrency))
+		gzipWriter, err := gzipWriters.WriterTo(file)
+		if err != nil {
+			_ = closers.Close()
+			return nil, nil, fmt.Errorf("cannot create gzip writer: %w", err)
+		}
+		closers.Append(gzipWriter.Close)
+		return gzipWriter, closers, nil
+	}
+
+	bufferWriter := pool.BufferedWriters(table.BufferSize).WriterTo(file)
+	closers.Append(bufferWriter.Flush)
+	return bufferWriter, closers, nil
+}
+
+// headerRow encodes the columns as a CSV row, all values are enclosed.
+func headerRow(columns []string, delimiter, enclosure byte) []byte {
+	var out []byte
+	for i, column := range columns {
+		if i > 0 {
+			out = append(out, delimiter)
+		}
+		out = append(out, enclosure)
+		for j := 0; j < len(column); j++ {
+			// Escape the enclosure by doubling it
+			if column[j] == enclosure {
+				out = append(out, enclosure)
+			}
+			out = append(out, column[j])
+		}
+		out = append(out, enclosure)
+	}
+	return append(out, '\n')
+}
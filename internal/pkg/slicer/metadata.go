@@ -0,0 +1,62 @@
+package slicer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	manifestPkg "github.com/keboola/processor-split-table/internal/pkg/manifest"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/profile"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/slicedwriter"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/typeinfer"
+)
+
+// PartsMetadata describes slices of the output table.
+// It is written to the Table.OutPartsMetadataPath, if the path is set.
+type PartsMetadata struct {
+	// SortBy columns, MinKey and MaxKey of each part contain values of these columns.
+	SortBy []string            `json:"sortBy,omitempty"`
+	Parts  []slicedwriter.Part `json:"parts"`
+}
+
+func writePartsMetadata(table Table, parts []slicedwriter.Part) error {
+	return writeJSON(table.OutPartsMetadataPath, "parts metadata", PartsMetadata{SortBy: table.SortBy, Parts: parts})
+}
+
+func writeProfile(table Table, p profile.Profile) error {
+	return writeJSON(table.OutProfilePath, "profile", p)
+}
+
+func writeResult(table Table, result *Result) error {
+	return writeJSON(table.OutResultPath, "result", result)
+}
+
+func writeJSON(path, description string, value any) error {
+	// Encode JSON
+	data, err := json.MarshalIndent(value, "", "    ")
+	if err != nil {
+		return fmt.Errorf("cannot encode %s to JSON: %w", description, err)
+	}
+
+	// Write to file
+	return os.WriteFile(path, data, kbc.NewFilePermissions)
+}
+
+// setColumnTypes stores the inferred column types to the manifest "column_metadata".
+func setColumnTypes(manifest *manifestPkg.Manifest, columns []typeinfer.Column) error {
+	for _, column := range columns {
+		nullable := "0"
+		if column.Nullable {
+			nullable = "1"
+		}
+		items := []manifestPkg.ColumnMetadata{
+			{Key: "KBC.datatype.basetype", Value: string(column.Type)},
+			{Key: "KBC.datatype.nullable", Value: nullable},
+		}
+		if err := manifest.SetColumnMetadata(column.Name, items); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// Package pipeline defines the common interface of the stages between the rowsreader.Reader and the slicedwriter.Writer.
+package pipeline
+
+import (
+	"encoding/binary"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+)
+
+// Writer consumes rows of the table, it is implemented by all stages and by the slicedwriter.Writer.
+// The row is valid only during the Write call, a stage that keeps the row must copy it.
+// Close flushes the buffered rows, if any, and closes the next stage.
+type Writer interface {
+	Write(row []byte) error
+	Close() error
+}
+
+// Aborter is implemented by the stages with temporary files.
+// Abort removes the files, if the slicing fails and the Close is not called or fails.
+type Aborter interface {
+	Abort() error
+}
+
+// ColumnIndexes maps column names to their indexes in the table columns.
+func ColumnIndexes(columns []string, names []string) ([]int, error) {
+	positions := make(map[string]int, len(columns))
+	for i, column := range columns {
+		positions[column] = i
+	}
+
+	out := make([]int, len(names))
+	for i, name := range names {
+		index, found := positions[name]
+		if !found {
+			return nil, kbc.UserErrorf(`column "%s" not found in the table columns`, name)
+		}
+		out[i] = index
+	}
+	return out, nil
+}
+
+// AppendKey appends the values of the key columns to the buffer.
+// Each value is prefixed by its length, so the key is unambiguous.
+// A missing value, for example in an empty row, is treated as an empty string.
+func AppendKey(buf []byte, fields [][]byte, keyColumns []int) []byte {
+	for _, index := range keyColumns {
+		var value []byte
+		if index < len(fields) {
+			value = fields[index]
+		}
+		buf = binary.AppendUvarint(buf, uint64(len(value)))
+		buf = append(buf, value...)
+	}
+	return buf
+}
+
+// CopyRow returns copy of the row, which always ends with a new line.
+// Stages that change the order of the rows must use it, otherwise the last row of the input could be merged with the next one.
+func CopyRow(row []byte) []byte {
+	length := len(row)
+	if length > 0 && row[length-1] == '\n' {
+		return append(make([]byte, 0, length), row...)
+	}
+	out := make([]byte, 0, length+1)
+	out = append(out, row...)
+	return append(out, '\n')
+}
@@ -0,0 +1,74 @@
+package profile
+
+import (
+	"math"
+	"math/bits"
+)
+
+const (
+	// hllPrecision defines number of the registers 2^p, the standard error is 1.04 / sqrt(2^p), ~0.8%.
+	hllPrecision = 14
+	hllRegisters = 1 << hllPrecision
+	// ColumnSize is the memory of the statistics of one column, the HyperLogLog registers, the min/max values are not included.
+	ColumnSize = hllRegisters
+)
+
+// hyperLogLog estimates the number of distinct values with a fixed memory usage.
+// The hash function is not randomized, so the estimate is deterministic.
+type hyperLogLog struct {
+	registers []uint8
+}
+
+func newHyperLogLog() *hyperLogLog {
+	return &hyperLogLog{registers: make([]uint8, hllRegisters)}
+}
+
+func (h *hyperLogLog) Add(value []byte) {
+	hash := hashValue(value)
+	index := hash >> (64 - hllPrecision)
+	// Position of the first 1 bit in the rest of the hash, the sentinel bit limits the rank
+	rank := uint8(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1))) + 1
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+}
+
+// Estimate returns the estimated number of distinct values.
+func (h *hyperLogLog) Estimate() uint64 {
+	sum := 0.0
+	zeros := 0
+	for _, register := range h.registers {
+		sum += 1 / float64(uint64(1)<<register)
+		if register == 0 {
+			zeros++
+		}
+	}
+
+	m := float64(hllRegisters)
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+
+	// Small range correction, the linear counting is more precise
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(math.Round(estimate))
+}
+
+// hashValue is the FNV-1a hash with the SplitMix64 finalizer, FNV alone doesn't distribute the high bits well enough.
+func hashValue(value []byte) uint64 {
+	// FNV-1a, inlined to avoid allocation of the hash.Hash
+	x := uint64(14695981039346656037)
+	for _, char := range value {
+		x ^= uint64(char)
+		x *= 1099511628211
+	}
+
+	// SplitMix64 finalizer
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
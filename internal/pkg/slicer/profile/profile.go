@@ -0,0 +1,131 @@
+// Package profile provides per-column statistics of the table, computed in the same pass as the slicing.
+//
+// For each column are computed:
+//   - Number of nulls, an empty value is a null.
+//   - Estimated number of distinct non-null values, by the HyperLogLog.
+//   - Minimum and maximum non-null value, numbers are compared as numbers, see sorter.AppendKey.
+//   - Maximum length of a value in characters.
+package profile
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/columnsparser"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/pipeline"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/sorter"
+)
+
+// Profile of the table, it is encoded to JSON.
+type Profile struct {
+	Rows    uint64   `json:"rows"`
+	Columns []Column `json:"columns"`
+}
+
+// Column statistics, Min and Max are empty, if all values are null.
+type Column struct {
+	Name      string `json:"name"`
+	Nulls     uint64 `json:"nulls"`
+	Distinct  uint64 `json:"distinct"`
+	Min       string `json:"min,omitempty"`
+	Max       string `json:"max,omitempty"`
+	MaxLength int    `json:"maxLength"`
+}
+
+// Profiler computes the Profile from the rows passed to the next stage.
+type Profiler struct {
+	next    pipeline.Writer
+	parser  *columnsparser.Parser
+	columns []string
+	states  []columnState
+	rows    uint64
+	key     []byte
+}
+
+type columnState struct {
+	nulls     uint64
+	distinct  *hyperLogLog
+	min       []byte
+	max       []byte
+	minKey    []byte // encoded min value, see sorter.AppendValue
+	maxKey    []byte // encoded max value
+	maxLength int
+}
+
+// New creates the Profiler stage.
+func New(next pipeline.Writer, columns []string, delimiter, enclosure byte) *Profiler {
+	states := make([]columnState, len(columns))
+	for i := range states {
+		states[i].distinct = newHyperLogLog()
+	}
+	return &Profiler{
+		next:    next,
+		parser:  columnsparser.NewParser(delimiter, enclosure),
+		columns: columns,
+		states:  states,
+	}
+}
+
+func (p *Profiler) Write(row []byte) error {
+	p.rows++
+
+	fields, err := p.parser.Fields(row)
+	if err != nil {
+		return fmt.Errorf("cannot parse the row %d to profile columns: %w", p.rows, err)
+	}
+
+	for i := range p.states {
+		state := &p.states[i]
+
+		// A missing value, for example in an empty row, is a null
+		var value []byte
+		if i < len(fields) {
+			value = fields[i]
+		}
+		if len(value) == 0 {
+			state.nulls++
+			continue
+		}
+
+		state.distinct.Add(value)
+
+		if length := utf8.RuneCount(value); length > state.maxLength {
+			state.maxLength = length
+		}
+
+		p.key = sorter.AppendValue(p.key[:0], value)
+		if state.minKey == nil || bytes.Compare(p.key, state.minKey) < 0 {
+			state.minKey = append(state.minKey[:0], p.key...)
+			state.min = append(state.min[:0], value...)
+		}
+		if state.maxKey == nil || bytes.Compare(p.key, state.maxKey) > 0 {
+			state.maxKey = append(state.maxKey[:0], p.key...)
+			state.max = append(state.max[:0], value...)
+		}
+	}
+
+	return p.next.Write(row)
+}
+
+// Close closes the next stage.
+func (p *Profiler) Close() error {
+	return p.next.Close()
+}
+
+// Profile returns the statistics, the result is complete after Close.
+func (p *Profiler) Profile() Profile {
+	out := Profile{Rows: p.rows, Columns: make([]Column, len(p.columns))}
+	for i, name := range p.columns {
+		state := p.states[i]
+		out.Columns[i] = Column{
+			Name:      name,
+			Nulls:     state.nulls,
+			Distinct:  min(state.distinct.Estimate(), p.rows-state.nulls), // the estimate can exceed the number of values
+			Min:       string(state.min),
+			Max:       string(state.max),
+			MaxLength: state.maxLength,
+		}
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package profile
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type rowsCollector struct {
+	rows   []string
+	closed bool
+}
+
+func (c *rowsCollector) Write(row []byte) error {
+	c.rows = append(c.rows, string(row))
+	return nil
+}
+
+func (c *rowsCollector) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestProfiler(t *testing.T) {
+	t.Parallel()
+
+	rows := []string{
+		"\"10\",\"b\",\"\"\n",
+		"\"9\",\"ččč\",\"\"\n",
+		"\"-1.5\",\"b\",\"\"\n",
+		"\"abc\"", // last row without new line, missing values are nulls
+	}
+
+	out := &rowsCollector{}
+	profiler := New(out, []string{"mixed", "string", "empty"}, ',', '"')
+	for _, row := range rows {
+		require.NoError(t, profiler.Write([]byte(row)))
+	}
+	require.NoError(t, profiler.Close())
+
+	// Rows are passed to the next stage without modification
+	assert.Equal(t, rows, out.rows)
+	assert.True(t, out.closed)
+
+	assert.Equal(t, Profile{
+		Rows: 4,
+		Columns: []Column{
+			// Numbers are compared as numbers and are ordered before other values
+			{Name: "mixed", Nulls: 0, Distinct: 4, Min: "-1.5", Max: "abc", MaxLength: 4},
+			// Length is in characters
+			{Name: "string", Nulls: 1, Distinct: 2, Min: "b", Max: "ččč", MaxLength: 3},
+			{Name: "empty", Nulls: 4, Distinct: 0, MaxLength: 0},
+		},
+	}, profiler.Profile())
+}
+
+func TestHyperLogLog(t *testing.T) {
+	t.Parallel()
+
+	for _, count := range []int{1, 100, 10_000, 200_000} {
+		h := newHyperLogLog()
+		for i := 0; i < count; i++ {
+			value := []byte(fmt.Sprintf("value %d", i))
+			h.Add(value)
+			h.Add(value) // duplicates don't affect the estimate
+		}
+		assert.InEpsilon(t, count, h.Estimate(), 0.03, fmt.Sprintf("count %d", count))
+	}
+}
@@ -0,0 +1,50 @@
+package slicer
+
+import (
+	"time"
+)
+
+const (
+	ResultSliced  = "sliced"  // the table has been sliced
+	ResultCopied  = "copied"  // the table is smaller than the threshold, it has been copied without modification
+	ResultSkipped = "skipped" // the table is smaller than the threshold, it has not been copied, see Table.InputSizeLowExitCode
+	ResultFailed  = "failed"  // see Result.Error
+)
+
+const (
+	ManifestCreated    = "created"    // the input manifest does not exist
+	ManifestUpdated    = "updated"    // the input manifest has been modified, for example, the columns have been added
+	ManifestUnaffected = "unaffected" // the input manifest has been written without modification
+	ManifestCopied     = "copied"     // the input manifest has been copied together with the copied table
+	ManifestNone       = "none"       // no output manifest has been written
+)
+
+// Result summarizes the SliceTable function for machines, the log message is intended for humans.
+// It is written to the Table.OutResultPath, if the path is set.
+// Bytes of a stream input are the bytes read, the compressed size of a stream output is not known.
+type Result struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Error is set if the Status is ResultFailed.
+	Error     string `json:"error,omitempty"`
+	InSlices  uint32 `json:"inSlices"`
+	OutSlices uint32 `json:"outSlices"`
+	InBytes   uint64 `json:"inBytes"`
+	// OutBytes is the size of the output, after compression, if any.
+	OutBytes uint64 `json:"outBytes"`
+	// OutBytesUncompressed is the size of the output before compression.
+	OutBytesUncompressed uint64 `json:"outBytesUncompressed"`
+	// Rows are not counted, if the table has been copied.
+	Rows            uint64  `json:"rows"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	// ThroughputBytesPerSecond is computed from the InBytes.
+	ThroughputBytesPerSecond float64 `json:"throughputBytesPerSecond"`
+	Manifest                 string  `json:"manifest"`
+}
+
+func (r *Result) setDuration(duration time.Duration) {
+	r.DurationSeconds = duration.Seconds()
+	if r.DurationSeconds > 0 {
+		r.ThroughputBytesPerSecond = float64(r.InBytes) / r.DurationSeconds
+	}
+}
@@ -0,0 +1,87 @@
+package rowsreader
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+)
+
+// DetectFormat returns format of the input table.
+// If the input format is "auto", the format is detected by the extension of the first slice.
+func DetectFormat(inputFormat config.Format, slices []string) (config.Format, error) {
+	switch inputFormat {
+	case config.FormatCSV, config.FormatParquet, config.FormatJSONL:
+		return inputFormat, nil
+	case config.FormatAuto:
+	default:
+		return 0, kbc.UserErrorf(`unexpected input format "%s"`, inputFormat)
+	}
+
+	if len(slices) == 0 {
+		return config.FormatCSV, nil
+	}
+
+	path := strings.TrimSuffix(slices[0], kbc.GzipFileExtension)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".parquet":
+		return config.FormatParquet, nil
+	case ".jsonl", ".ndjson":
+		return config.FormatJSONL, nil
+	default:
+		return config.FormatCSV, nil
+	}
+}
+
+// csvRowWriter writes converted rows of a non-CSV input.
+// All values are enclosed, so the rows can be reliably split by the reader.
+type csvRowWriter struct {
+	out       io.Writer
+	delimiter byte
+	enclosure byte
+	buffer    []byte
+}
+
+func newCSVRowWriter(out io.Writer, delimiter, enclosure byte) *csvRowWriter {
+	return &csvRowWriter{out: out, delimiter: delimiter, enclosure: enclosure}
+}
+
+func (w *csvRowWriter) WriteRow(values []string) error {
+	w.buffer = w.buffer[:0]
+	for i, value := range values {
+		if i > 0 {
+			w.buffer = append(w.buffer, w.delimiter)
+		}
+		w.buffer = append(w.buffer, w.enclosure)
+		for j := 0; j < len(value); j++ {
+			// Escape the enclosure by doubling it
+			if value[j] == w.enclosure {
+				w.buffer = append(w.buffer, w.enclosure)
+			}
+			w.buffer = append(w.buffer, value[j])
+		}
+		w.buffer = append(w.buffer, w.enclosure)
+	}
+	w.buffer = append(w.buffer, '\n')
+	_, err := w.out.Write(w.buffer)
+	return err
+}
+
+// unionColumns adds new columns to the list, the order of the first occurrence is preserved.
+type unionColumns struct {
+	columns []string
+	indexes map[string]int
+}
+
+func newUnionColumns() *unionColumns {
+	return &unionColumns{indexes: make(map[string]int)}
+}
+
+func (u *unionColumns) Add(column string) {
+	if _, found := u.indexes[column]; !found {
+		u.indexes[column] = len(u.columns)
+		u.columns = append(u.columns, column)
+	}
+}
@@ -0,0 +1,170 @@
+package rowsreader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/apache/arrow/go/v14/arrow/array"
+	"github.com/apache/arrow/go/v14/arrow/decimal128"
+	"github.com/apache/arrow/go/v14/arrow/memory"
+	"github.com/apache/arrow/go/v14/parquet/pqarrow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+)
+
+func TestDetectFormat(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		inputFormat config.Format
+		path        string
+		expected    config.Format
+	}{
+		{inputFormat: config.FormatAuto, path: "table.csv", expected: config.FormatCSV},
+		{inputFormat: config.FormatAuto, path: "table.csv.gz", expected: config.FormatCSV},
+		{inputFormat: config.FormatAuto, path: "part0001", expected: config.FormatCSV},
+		{inputFormat: config.FormatAuto, path: "table.parquet", expected: config.FormatParquet},
+		{inputFormat: config.FormatAuto, path: "table.jsonl", expected: config.FormatJSONL},
+		{inputFormat: config.FormatAuto, path: "table.NDJSON.gz", expected: config.FormatJSONL},
+		{inputFormat: config.FormatJSONL, path: "table.csv", expected: config.FormatJSONL},
+		{inputFormat: config.FormatCSV, path: "table.parquet", expected: config.FormatCSV},
+	}
+	for _, c := range cases {
+		format, err := DetectFormat(c.inputFormat, []string{c.path})
+		require.NoError(t, err)
+		assert.Equal(t, c.expected, format, c.path)
+	}
+}
+
+func TestReadJSONLFile(t *testing.T) {
+	t.Parallel()
+
+	_, testFile, _, _ := runtime.Caller(0)
+	rootDir := filepath.Dir(testFile)
+
+	cfg := config.Default()
+	reader, err := NewFileReader(context.Background(), newTestProgressLogger(), cfg, filepath.Join(rootDir, "fixtures", "rows.jsonl"), ',', '"')
+	require.NoError(t, err)
+
+	// Columns are the union of the keys
+	header, err := reader.Header()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "tags", "active", "score", "nested"}, header)
+
+	var rows []string
+	for reader.Read() {
+		rows = append(rows, string(reader.Bytes()))
+	}
+	require.NoError(t, reader.Close())
+	assert.Equal(t, []string{
+		"\"1\",\"Alice \"\"A\"\"\",\"[\"\"a\"\",\"\"b\"\"]\",\"true\",\"\",\"\"\n",
+		"\"2\",\"\",\"\",\"\",\"10.5\",\"\"\n",
+		"\"3\",\"Bob\",\"\",\"\",\"\",\"{\"\"x\"\":1}\"\n",
+	}, rows)
+}
+
+func TestReadJSONLSlices(t *testing.T) {
+	t.Parallel()
+
+	_, testFile, _, _ := runtime.Caller(0)
+	rootDir := filepath.Dir(testFile)
+
+	path := filepath.Join(rootDir, "fixtures", "sliced.jsonl")
+	slices, err := kbc.FindSlices(path)
+	require.NoError(t, err)
+
+	reader, err := NewSlicesReader(context.Background(), newTestProgressLogger(), config.Default(), path, slices, ';', '\'')
+	require.NoError(t, err)
+
+	header, err := reader.Header()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "a", "b"}, header)
+
+	var rows []string
+	for reader.Read() {
+		rows = append(rows, string(reader.Bytes()))
+	}
+	require.NoError(t, reader.Close())
+	assert.Equal(t, []string{"'1';'x';''\n", "'2';'';'y'\n"}, rows)
+}
+
+func TestReadJSONLInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, testFile, _, _ := runtime.Caller(0)
+	rootDir := filepath.Dir(testFile)
+
+	_, err := NewFileReader(context.Background(), newTestProgressLogger(), config.Default(), filepath.Join(rootDir, "fixtures", "invalid.jsonl"), ',', '"')
+	if assert.Error(t, err) {
+		assert.Equal(t, `invalid JSON object at line 2 of "invalid.jsonl": expected an object`, err.Error())
+	}
+}
+
+func TestReadParquetFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "table.parquet")
+	writeTestParquet(t, path)
+
+	reader, err := NewFileReader(context.Background(), newTestProgressLogger(), config.Default(), path, ',', '"')
+	require.NoError(t, err)
+	assert.Equal(t, config.FormatParquet, reader.Format())
+
+	// Columns are defined by the schema
+	header, err := reader.Header()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "price", "created", "note", "amount"}, header)
+
+	var rows []string
+	for reader.Read() {
+		rows = append(rows, string(reader.Bytes()))
+	}
+	require.NoError(t, reader.Close())
+	assert.Equal(t, []string{
+		"\"1\",\"10.5\",\"2023-01-31 10:20:30Z\",\"a \"\"b\"\"\",\"12345678901234.567891\"\n",
+		"\"2\",\"\",\"2023-02-01 00:00:00.5Z\",\"\",\"-0.000001\"\n",
+	}, rows)
+}
+
+func writeTestParquet(t *testing.T, path string) {
+	t.Helper()
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "price", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: "created", Type: &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}},
+		{Name: "note", Type: arrow.BinaryTypes.String},
+		{Name: "amount", Type: &arrow.Decimal128Type{Precision: 20, Scale: 6}},
+	}, nil)
+
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	builder.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2}, nil)
+	builder.Field(1).(*array.Float64Builder).AppendValues([]float64{10.5, 0}, []bool{true, false})
+	builder.Field(2).(*array.TimestampBuilder).AppendValues([]arrow.Timestamp{
+		arrow.Timestamp(time.Date(2023, 1, 31, 10, 20, 30, 0, time.UTC).UnixMicro()),
+		arrow.Timestamp(time.Date(2023, 2, 1, 0, 0, 0, 500_000_000, time.UTC).UnixMicro()),
+	}, nil)
+	builder.Field(3).(*array.StringBuilder).AppendValues([]string{`a "b"`, ""}, nil)
+	builder.Field(4).(*array.Decimal128Builder).AppendValues([]decimal128.Num{
+		decimal128.FromU64(12345678901234567891),
+		decimal128.FromI64(-1),
+	}, nil)
+	record := builder.NewRecord()
+	defer record.Release()
+
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	writer, err := pqarrow.NewFileWriter(schema, file, nil, pqarrow.DefaultWriterProps())
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+}
@@ -0,0 +1,142 @@
+package rowsreader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+)
+
+// jsonlColumns returns the union of the keys of all objects, in the order of the first occurrence.
+func (r *Reader) jsonlColumns() ([]string, error) {
+	union := newUnionColumns()
+	for _, path := range r.slices {
+		err := r.readJSONLSlice(path, func(keys []string, _ []json.RawMessage) error {
+			for _, key := range keys {
+				union.Add(key)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return union.columns, nil
+}
+
+// jsonlToCSV converts each JSON object to a CSV row, a missing key or a null is an empty value.
+// Strings are unquoted, other values, including nested objects and arrays, are written as JSON.
+func (r *Reader) jsonlToCSV(out io.Writer, in io.Reader, path string) error {
+	writer := newCSVRowWriter(out, r.delimiter, r.enclosure)
+	values := make([]string, len(r.columns))
+	indexes := make(map[string]int, len(r.columns))
+	for i, column := range r.columns {
+		indexes[column] = i
+	}
+
+	return scanJSONL(in, path, func(keys []string, rawValues []json.RawMessage) error {
+		for i := range values {
+			values[i] = ""
+		}
+		for i, key := range keys {
+			index, found := indexes[key]
+			if !found {
+				return fmt.Errorf(`unexpected key "%s" in JSON file "%s", it is not defined in the columns`, key, filepath.Base(path))
+			}
+			value, err := jsonValue(rawValues[i])
+			if err != nil {
+				return err
+			}
+			values[index] = value
+		}
+		return writer.WriteRow(values)
+	})
+}
+
+func (r *Reader) readJSONLSlice(path string, fn func(keys []string, values []json.RawMessage) error) error {
+	in, err := r.openSliceFile(path)
+	if err != nil {
+		return err
+	}
+	readErr := scanJSONL(in, path, fn)
+	closeErr := in.Close()
+	if readErr != nil {
+		return readErr
+	}
+	return closeErr
+}
+
+// scanJSONL calls the callback for each object in the JSON Lines file, empty lines are skipped.
+func scanJSONL(in io.Reader, path string, fn func(keys []string, values []json.RawMessage) error) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, StartTokenBufferSize), MaxTokenBufferSize)
+	var keys []string
+	var values []json.RawMessage
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var err error
+		keys, values, err = parseJSONObject(scanner.Bytes(), keys[:0], values[:0])
+		if err != nil {
+			return kbc.UserErrorf(`invalid JSON object at line %d of "%s": %w`, line, filepath.Base(path), err)
+		}
+		if err := fn(keys, values); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// parseJSONObject returns keys and raw values of the object, in the original order.
+func parseJSONObject(data []byte, keys []string, values []json.RawMessage) ([]string, []json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil {
+		return nil, nil, err
+	} else if token != json.Delim('{') {
+		return nil, nil, errors.New("expected an object")
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, token.(string))
+		values = append(values, value)
+	}
+
+	// Closing bracket
+	if _, err := decoder.Token(); err != nil {
+		return nil, nil, err
+	}
+	if decoder.More() {
+		return nil, nil, errors.New("unexpected data after the object")
+	}
+	return keys, values, nil
+}
+
+func jsonValue(raw json.RawMessage) (string, error) {
+	switch raw[0] {
+	case 'n':
+		return "", nil
+	case '"':
+		var str string
+		if err := json.Unmarshal(raw, &str); err != nil {
+			return "", fmt.Errorf("cannot decode JSON string: %w", err)
+		}
+		return str, nil
+	default:
+		return string(raw), nil
+	}
+}
@@ -0,0 +1,136 @@
+package rowsreader
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/apache/arrow/go/v14/arrow/array"
+	"github.com/apache/arrow/go/v14/arrow/decimal128"
+	"github.com/apache/arrow/go/v14/arrow/memory"
+	"github.com/apache/arrow/go/v14/parquet"
+	"github.com/apache/arrow/go/v14/parquet/file"
+	"github.com/apache/arrow/go/v14/parquet/pqarrow"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+)
+
+// parquetBatchSize is the number of rows read at once.
+const parquetBatchSize = 64 * 1024
+
+// parquetColumns returns the union of the fields of all slices, in the order of the first occurrence.
+func (r *Reader) parquetColumns() ([]string, error) {
+	union := newUnionColumns()
+	for _, path := range r.slices {
+		reader, err := file.OpenParquetFile(path, false)
+		if err != nil {
+			return nil, kbc.UserErrorf(`cannot open parquet file "%s": %w`, filepath.Base(path), err)
+		}
+		schema, err := pqarrow.FromParquet(reader.MetaData().Schema, nil, reader.MetaData().KeyValueMetadata())
+		closeErr := reader.Close()
+		if err != nil {
+			return nil, kbc.UserErrorf(`cannot read schema of parquet file "%s": %w`, filepath.Base(path), err)
+		} else if closeErr != nil {
+			return nil, closeErr
+		}
+		for _, field := range schema.Fields() {
+			union.Add(field.Name)
+		}
+	}
+	return union.columns, nil
+}
+
+// parquetToCSV converts each row of the parquet file to a CSV row, a missing field or a null is an empty value.
+func (r *Reader) parquetToCSV(out io.Writer, in parquet.ReaderAtSeeker, path string) error {
+	reader, err := file.NewParquetReader(in)
+	if err != nil {
+		return kbc.UserErrorf(`cannot open parquet file "%s": %w`, filepath.Base(path), err)
+	}
+	defer reader.Close()
+
+	arrowReader, err := pqarrow.NewFileReader(reader, pqarrow.ArrowReadProperties{BatchSize: parquetBatchSize}, memory.DefaultAllocator)
+	if err != nil {
+		return kbc.UserErrorf(`cannot read parquet file "%s": %w`, filepath.Base(path), err)
+	}
+
+	records, err := arrowReader.GetRecordReader(context.Background(), nil, nil)
+	if err != nil {
+		return kbc.UserErrorf(`cannot read parquet file "%s": %w`, filepath.Base(path), err)
+	}
+	defer records.Release()
+
+	// Map the fields of the file to the columns
+	fieldIndexes := make([]int, len(r.columns))
+	for i, column := range r.columns {
+		fieldIndexes[i] = -1
+		if indexes := records.Schema().FieldIndices(column); len(indexes) > 0 {
+			fieldIndexes[i] = indexes[0]
+		}
+	}
+
+	writer := newCSVRowWriter(out, r.delimiter, r.enclosure)
+	values := make([]string, len(r.columns))
+	for records.Next() {
+		record := records.Record()
+		for row := 0; row < int(record.NumRows()); row++ {
+			for i, fieldIndex := range fieldIndexes {
+				values[i] = ""
+				if fieldIndex >= 0 {
+					values[i] = parquetValue(record.Column(fieldIndex), row)
+				}
+			}
+			if err := writer.WriteRow(values); err != nil {
+				return err
+			}
+		}
+	}
+	if err := records.Err(); err != nil && err != io.EOF {
+		return kbc.UserErrorf(`cannot read parquet file "%s": %w`, filepath.Base(path), err)
+	}
+	return nil
+}
+
+// parquetValue formats the value, so it can be recognized by the type inference.
+func parquetValue(column arrow.Array, row int) string {
+	if column.IsNull(row) {
+		return ""
+	}
+	switch c := column.(type) {
+	case *array.String:
+		return c.Value(row)
+	case *array.LargeString:
+		return c.Value(row)
+	case *array.Float32:
+		return strconv.FormatFloat(float64(c.Value(row)), 'f', -1, 32)
+	case *array.Float64:
+		return strconv.FormatFloat(c.Value(row), 'f', -1, 64)
+	case *array.Decimal128:
+		return formatDecimal128(c.Value(row), c.DataType().(*arrow.Decimal128Type).Scale)
+	case *array.Timestamp:
+		t := c.DataType().(*arrow.TimestampType)
+		zone, _ := t.GetZone() // the zone of a parquet timestamp is "UTC" or empty
+		return c.Value(row).ToTime(t.Unit).In(zone).Format("2006-01-02 15:04:05.999999999Z07:00")
+	default:
+		return column.ValueStr(row)
+	}
+}
+
+// formatDecimal128 formats the decimal exactly, without the exponent notation, for example "-0.000001".
+func formatDecimal128(value decimal128.Num, scale int32) string {
+	digits := value.Abs().BigInt().String()
+	sign := ""
+	if value.Sign() < 0 {
+		sign = "-"
+	}
+	if scale <= 0 {
+		return sign + digits + strings.Repeat("0", int(-scale))
+	}
+	if pad := int(scale) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	point := len(digits) - int(scale)
+	return sign + digits[:point] + "." + digits[point:]
+}
@@ -1,36 +1,65 @@
 // Package progress provides slicing progress logger.
-// An input reader can be wrapped by the Logger.NewMeter method.
+// An input reader can be wrapped by the Logger.NewMeter or the Logger.NewReaderAtMeter method.
 package progress
 
 import (
+	"fmt"
 	"io"
+	"math"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	clock "github.com/benbjohnson/clock"
 	"github.com/c2h5oh/datasize"
 	"github.com/cenkalti/backoff/v4"
+	"github.com/dustin/go-humanize"
 
 	"github.com/keboola/processor-split-table/internal/pkg/log"
+	"github.com/keboola/processor-split-table/internal/pkg/metrics"
 	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/utils"
 )
 
+// throughputSmoothing is the weight of the last interval in the moving average of the throughput, used for the ETA.
+const throughputSmoothing = 0.3
+
 type Logger struct {
 	logger  log.Logger
-	total   datasize.ByteSize
+	clock   clock.Clock
+	start   time.Time
+	total   datasize.ByteSize // 0 if the input size is unknown, for example the standard input
 	read    datasize.ByteSize
 	lock    *sync.Mutex
 	timer   *clock.Timer
+	closed  bool
 	backoff *backoff.ExponentialBackOff
 	message string
+	metrics *metrics.Table // optional, see TrackMetrics
+
+	// Throughput of the last interval and its moving average
+	lastTime      time.Time
+	lastRead      datasize.ByteSize
+	throughputAvg float64 // bytes per second, 0 if there is no interval yet
+
+	// Statistics of the writer, see Writer.TrackProgress, they are updated without the lock
+	rows atomic.Uint64
+	part atomic.Uint32 // 0 if no writer is tracked
 }
 
+// NewLogger creates the progress logger.
+// If the total size is unknown, 0, the read bytes and the throughput are logged instead of the percentage.
 func NewLogger(clk clock.Clock, logger log.Logger, interval config.LogIntervalConfig, total datasize.ByteSize, message string) *Logger {
 	r := &Logger{
-		logger:  logger,
-		total:   total,
-		lock:    &sync.Mutex{},
-		backoff: newBackoff(interval),
-		message: message,
+		logger:   logger,
+		clock:    clk,
+		start:    clk.Now(),
+		total:    total,
+		lastTime: clk.Now(),
+		lock:     &sync.Mutex{},
+		backoff:  newBackoff(interval),
+		message:  message,
 	}
 
 	// Schedule the first log message.
@@ -45,18 +74,120 @@ func (r *Logger) NewMeter(reader io.Reader) io.Reader {
 	return &meter{Logger: r, reader: reader}
 }
 
+// NewReaderAtMeter measures random access reads, for example of a parquet file.
+func (r *Logger) NewReaderAtMeter(reader ReaderAtSeeker) ReaderAtSeeker {
+	return &readerAtMeter{Logger: r, ReaderAtSeeker: reader}
+}
+
+// Read returns the number of bytes read so far, before decompression.
+func (r *Logger) Read() datasize.ByteSize {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.read
+}
+
+// AddRow increments the rows written, it is called by the writer for each row.
+func (r *Logger) AddRow() {
+	r.rows.Add(1)
+}
+
+// SetPart sets the number of the current output part, it is called by the writer for each new part.
+func (r *Logger) SetPart(part uint32) {
+	r.part.Store(part)
+}
+
+// TrackMetrics enables counting of the read bytes also in the metrics.
+// It must be called before the first read.
+func (r *Logger) TrackMetrics(m *metrics.Table) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.metrics = m
+}
+
+// Close stops the logging, a message in progress is not rescheduled.
 func (r *Logger) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.closed = true
 	r.timer.Stop()
 	return nil
 }
 
 func (r *Logger) log() {
 	r.lock.Lock()
-	r.logger.Infof(`%s %05.2f%%`, r.message, float64(r.read*100)/float64(r.total))
-	r.lock.Unlock()
+	if r.closed {
+		r.lock.Unlock()
+		return
+	}
+	r.updateThroughput()
+	throughput := datasize.ByteSize(r.throughputAvg)
+
+	// Read bytes, the percentage and the ETA are known only if the total size is known
+	var parts []string
+	fields := []any{"bytesRead", r.read.Bytes()}
+	if r.total == 0 {
+		parts = append(parts, fmt.Sprintf(`%s %s`, r.message, humanReadable(r.read)))
+	} else {
+		percent := float64(r.read*100) / float64(r.total)
+		parts = append(parts, fmt.Sprintf(`%s %05.2f%%, %s / %s`, r.message, percent, humanReadable(r.read), humanReadable(r.total)))
+		fields = append(fields, "bytesTotal", r.total.Bytes(), "percent", math.Round(percent*100)/100)
+	}
+	parts = append(parts, humanReadable(throughput)+"/s")
+	fields = append(fields, "bytesPerSecond", throughput.Bytes())
+	if eta, ok := r.eta(); ok {
+		parts = append(parts, "ETA "+eta.String())
+		fields = append(fields, "etaSeconds", int64(eta.Seconds()))
+	}
+
+	// Statistics of the writer, if any
+	if part := r.part.Load(); part > 0 {
+		rows := r.rows.Load()
+		parts = append(parts, humanize.Comma(int64(rows))+" rows", fmt.Sprintf("part %d", part))
+		fields = append(fields, "rows", rows, "part", part)
+	}
+
+	r.logger.Infow(strings.Join(parts, ", "), fields...)
 
 	// Schedule next log message
 	r.timer.Reset(r.backoff.NextBackOff())
+	r.lock.Unlock()
+}
+
+// updateThroughput adds the throughput of the last interval to the moving average.
+func (r *Logger) updateThroughput() {
+	now := r.clock.Now()
+	elapsed := now.Sub(r.lastTime).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	current := float64(r.read-r.lastRead) / elapsed
+	if r.lastTime.Equal(r.start) {
+		r.throughputAvg = current
+	} else {
+		r.throughputAvg = throughputSmoothing*current + (1-throughputSmoothing)*r.throughputAvg
+	}
+	r.lastTime = now
+	r.lastRead = r.read
+}
+
+// eta estimates the remaining time from the moving average of the throughput, if the total size is known.
+func (r *Logger) eta() (time.Duration, bool) {
+	if r.total == 0 || r.throughputAvg <= 0 || r.read > r.total {
+		return 0, false
+	}
+	seconds := float64(r.total-r.read) / r.throughputAvg
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second), true
+}
+
+func humanReadable(v datasize.ByteSize) string {
+	return utils.RemoveSpaces(v.HumanReadable())
+}
+
+// ReaderAtSeeker is a random access reader.
+type ReaderAtSeeker interface {
+	io.ReaderAt
+	io.Seeker
 }
 
 type meter struct {
@@ -69,6 +200,23 @@ func (r *meter) Read(b []byte) (n int, err error) {
 	if err == nil && n != 0 {
 		r.lock.Lock()
 		r.read += datasize.ByteSize(n)
+		r.metrics.AddBytesRead(n)
+		r.lock.Unlock()
+	}
+	return
+}
+
+type readerAtMeter struct {
+	*Logger
+	ReaderAtSeeker
+}
+
+func (r *readerAtMeter) ReadAt(b []byte, off int64) (n int, err error) {
+	n, err = r.ReaderAtSeeker.ReadAt(b, off)
+	if n != 0 {
+		r.lock.Lock()
+		r.read += datasize.ByteSize(n)
+		r.metrics.AddBytesRead(n)
 		r.lock.Unlock()
 	}
 	return
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/benbjohnson/clock"
+	"github.com/c2h5oh/datasize"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -49,45 +50,122 @@ func TestLogger(t *testing.T) {
 
 	// The first message is logged after minute
 	assertLogsAfter(1*time.Minute, `
-INFO  progress message 00.00%
+INFO  progress message 00.00%, 0B / 11B, 0B/s  {"bytesRead": 0, "bytesTotal": 11, "percent": 0, "bytesPerSecond": 0}
 `)
 
 	// Add 5 minutes
 	assertLogsAfter(5*time.Minute, `
-INFO  progress message 00.00%
-INFO  progress message 00.00%
-INFO  progress message 00.00%
+INFO  progress message 00.00%, 0B / 11B, 0B/s  {"bytesRead": 0, "bytesTotal": 11, "percent": 0, "bytesPerSecond": 0}
+INFO  progress message 00.00%, 0B / 11B, 0B/s  {"bytesRead": 0, "bytesTotal": 11, "percent": 0, "bytesPerSecond": 0}
+INFO  progress message 00.00%, 0B / 11B, 0B/s  {"bytesRead": 0, "bytesTotal": 11, "percent": 0, "bytesPerSecond": 0}
 `)
 
 	// Read 3 bytes and add 10 minutes
 	_, err := io.ReadAll(r1)
 	require.NoError(t, err)
 	assertLogsAfter(10*time.Minute, `
-INFO  progress message 27.27%
-INFO  progress message 27.27%
+INFO  progress message 27.27%, 3B / 11B, 0B/s, ETA 30m0s  {"bytesRead": 3, "bytesTotal": 11, "percent": 27.27, "bytesPerSecond": 0, "etaSeconds": 1800}
+INFO  progress message 27.27%, 3B / 11B, 0B/s, ETA 42m51s  {"bytesRead": 3, "bytesTotal": 11, "percent": 27.27, "bytesPerSecond": 0, "etaSeconds": 2571}
 `)
 
 	// Read 3 bytes and add 10 minutes
 	_, err = io.ReadAll(r2)
 	require.NoError(t, err)
 	assertLogsAfter(10*time.Minute, `
-INFO  progress message 54.55%
+INFO  progress message 54.55%, 6B / 11B, 0B/s, ETA 20m4s  {"bytesRead": 6, "bytesTotal": 11, "percent": 54.55, "bytesPerSecond": 0, "etaSeconds": 1204}
 `)
 
 	// Read last 5 bytes and add 10 minutes
 	_, err = io.ReadAll(r3)
 	require.NoError(t, err)
 	assertLogsAfter(10*time.Minute, `
-INFO  progress message 100.00%
+INFO  progress message 100.00%, 11B / 11B, 0B/s, ETA 0s  {"bytesRead": 11, "bytesTotal": 11, "percent": 100, "bytesPerSecond": 0, "etaSeconds": 0}
 `)
 
 	// Add 30 minutes
 	assertLogsAfter(30*time.Minute, `
-INFO  progress message 100.00%
-INFO  progress message 100.00%
+INFO  progress message 100.00%, 11B / 11B, 0B/s, ETA 0s  {"bytesRead": 11, "bytesTotal": 11, "percent": 100, "bytesPerSecond": 0, "etaSeconds": 0}
+INFO  progress message 100.00%, 11B / 11B, 0B/s, ETA 0s  {"bytesRead": 11, "bytesTotal": 11, "percent": 100, "bytesPerSecond": 0, "etaSeconds": 0}
 `)
 }
 
+func TestLogger_UnknownTotal(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewMock()
+
+	logs := &syncBuffer{}
+	logger := newDebugLogger(logs)
+
+	// Create progress logger, the total size is unknown
+	interval := config.LogIntervalConfig{Multiplier: 2, Initial: time.Minute, Maximum: 15 * time.Minute}
+	progress := NewLogger(clk, logger, interval, 0, "progress message")
+	progress.SetPart(1)
+	progress.SetPart(2) // overrides the previous value
+	progress.AddRow()
+	r := progress.NewMeter(bytes.NewReader(make([]byte, 6*1024)))
+
+	// The read bytes, the throughput and the statistics of the writer are logged
+	_, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, 6*datasize.KB, progress.Read())
+	clk.Add(time.Minute)
+	expected := `INFO  progress message 6.0KB, 102B/s, 1 rows, part 2  {"bytesRead": 6144, "bytesPerSecond": 102, "rows": 1, "part": 2}`
+	assert.Eventually(t, func() bool { return expected == strings.TrimSpace(logs.String()) }, time.Second, time.Millisecond)
+	assert.Equal(t, expected, strings.TrimSpace(logs.String()))
+}
+
+func TestLogger_Close(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewMock()
+
+	logs := &syncBuffer{}
+	logger := newDebugLogger(logs)
+
+	interval := config.LogIntervalConfig{Multiplier: 2, Initial: time.Minute, Maximum: 15 * time.Minute}
+	progress := NewLogger(clk, logger, interval, 0, "progress message")
+	clk.Add(time.Minute)
+	assert.Eventually(t, func() bool { return logs.String() != "" }, time.Second, time.Millisecond)
+
+	// No message is logged after Close
+	require.NoError(t, progress.Close())
+	logs.Reset()
+	clk.Add(time.Hour)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, "", logs.String())
+}
+
+func TestLogger_ETA(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewMock()
+
+	logs := &syncBuffer{}
+	logger := newDebugLogger(logs)
+
+	// Create progress logger, messages are logged each minute
+	interval := config.LogIntervalConfig{Multiplier: 1, Initial: time.Minute, Maximum: time.Minute}
+	progress := NewLogger(clk, logger, interval, 240, "progress message")
+	progress.SetPart(1)
+
+	readAndLog := func(n int, expected string) {
+		_, err := io.ReadAll(progress.NewMeter(bytes.NewReader(make([]byte, n))))
+		require.NoError(t, err)
+		progress.AddRow()
+		clk.Add(time.Minute)
+		assert.Eventually(t, func() bool { return expected == strings.TrimSpace(logs.String()) }, time.Second, time.Millisecond)
+		assert.Equal(t, expected, strings.TrimSpace(logs.String()))
+		logs.Reset()
+	}
+
+	// 1B/s, 180B remaining
+	readAndLog(60, `INFO  progress message 25.00%, 60B / 240B, 1B/s, ETA 3m0s, 1 rows, part 1  {"bytesRead": 60, "bytesTotal": 240, "percent": 25, "bytesPerSecond": 1, "etaSeconds": 180, "rows": 1, "part": 1}`)
+
+	// 2B/s in the last minute, the moving average is 1.3B/s, 60B remaining
+	readAndLog(120, `INFO  progress message 75.00%, 180B / 240B, 1B/s, ETA 46s, 2 rows, part 1  {"bytesRead": 180, "bytesTotal": 240, "percent": 75, "bytesPerSecond": 1, "etaSeconds": 46, "rows": 2, "part": 1}`)
+}
+
 func TestBackoff(t *testing.T) {
 	t.Parallel()
 	b := newBackoff(config.LogIntervalConfig{Multiplier: 2, Initial: time.Minute, Maximum: 15 * time.Minute})
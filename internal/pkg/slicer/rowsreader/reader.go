@@ -3,6 +3,7 @@ package rowsreader
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -11,6 +12,8 @@ import (
 
 	"github.com/c2h5oh/datasize"
 	"github.com/klauspost/readahead"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/keboola/processor-split-table/internal/pkg/kbc"
@@ -19,6 +22,7 @@ import (
 	"github.com/keboola/processor-split-table/internal/pkg/slicer/columnsparser"
 	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
 	"github.com/keboola/processor-split-table/internal/pkg/slicer/rowsreader/progress"
+	"github.com/keboola/processor-split-table/internal/pkg/tracing"
 )
 
 const (
@@ -26,18 +30,27 @@ const (
 	StartTokenBufferSize = int(8 * datasize.MB)
 	// MaxTokenBufferSize specifies maximal size of the scanner buffer, it is also maximum length of a CSV row.
 	MaxTokenBufferSize = int(50 * datasize.MB)
+	// gzipMagicBytes are the first bytes of a gzip stream.
+	gzipMagicBytes = "\x1f\x8b"
 )
 
 // Reader reads rows from the CSV table.
 // When slicing, we do not need to decode the individual columns, we just need to reliably determine the rows.
 // Therefore, this own/fast implementation.
+//
+// Parquet and JSON Lines tables are converted to CSV rows, see DetectFormat.
+// Columns of these formats are defined by the union of the schemas or the keys, see Reader.Header.
 type Reader struct {
+	ctx       context.Context // parent of the input slices spans
 	config    config.Config
 	path      string
 	slices    []string
 	sliced    bool
+	format    config.Format
+	columns   []string // columns of a non-CSV format
 	delimiter byte
 	enclosure byte
+	stream    io.Reader // the only input, if the table is read from a stream, see NewStreamReader
 
 	rowCounter uint64
 
@@ -51,21 +64,35 @@ type Reader struct {
 type sliceReadCloser struct {
 	io.Reader
 	closer.Closers
+	path     string
+	readerAt progress.ReaderAtSeeker // parquet slices are read randomly
+	span     trace.Span              // ends when the slice is closed, see Reader.openSlice
 }
 
 // NewSlicesReader creates the Reader for a sliced CSV table.
-func NewSlicesReader(progress *progress.Logger, cfg config.Config, path string, slices kbc.Slices, delimiter byte, enclosure byte) (*Reader, error) {
-	return newReader(progress, cfg, path, slices.Paths(), true, delimiter, enclosure)
+func NewSlicesReader(ctx context.Context, progress *progress.Logger, cfg config.Config, path string, slices kbc.Slices, delimiter byte, enclosure byte) (*Reader, error) {
+	return newReader(ctx, progress, cfg, path, slices.Paths(), true, delimiter, enclosure)
 }
 
 // NewFileReader creates the Reader for a single CSV file.
 // It is special case of the slices reader with only one slice.
-func NewFileReader(progress *progress.Logger, cfg config.Config, path string, delimiter byte, enclosure byte) (*Reader, error) {
-	return newReader(progress, cfg, path, []string{path}, false, delimiter, enclosure)
+func NewFileReader(ctx context.Context, progress *progress.Logger, cfg config.Config, path string, delimiter byte, enclosure byte) (*Reader, error) {
+	return newReader(ctx, progress, cfg, path, []string{path}, false, delimiter, enclosure)
 }
 
-func newReader(progress *progress.Logger, cfg config.Config, path string, slices []string, sliced bool, delimiter byte, enclosure byte) (*Reader, error) {
+// NewStreamReader creates the Reader for a CSV table read from the stream, for example STDIN.
+// Compression is detected by the gzip magic bytes. Other formats are not supported, they are read twice or randomly.
+func NewStreamReader(ctx context.Context, progress *progress.Logger, cfg config.Config, stream io.Reader, delimiter byte, enclosure byte) (*Reader, error) {
+	return newReaderFrom(ctx, progress, cfg, kbc.StdStreamPath, []string{kbc.StdStreamPath}, false, stream, delimiter, enclosure)
+}
+
+func newReader(ctx context.Context, progress *progress.Logger, cfg config.Config, path string, slices []string, sliced bool, delimiter byte, enclosure byte) (*Reader, error) {
+	return newReaderFrom(ctx, progress, cfg, path, slices, sliced, nil, delimiter, enclosure)
+}
+
+func newReaderFrom(ctx context.Context, progress *progress.Logger, cfg config.Config, path string, slices []string, sliced bool, stream io.Reader, delimiter byte, enclosure byte) (*Reader, error) {
 	reader := &Reader{
+		ctx:          ctx,
 		progress:     progress,
 		config:       cfg,
 		path:         path,
@@ -73,10 +100,37 @@ func newReader(progress *progress.Logger, cfg config.Config, path string, slices
 		delimiter:    delimiter,
 		enclosure:    enclosure,
 		sliced:       sliced,
+		stream:       stream,
 		gzipReaders:  pool.GZIPReaders(),
 		aheadBuffers: pool.ReadAheadBuffers(int(cfg.AheadBlocks), cfg.AheadBlockSize),
 	}
 
+	// Get columns of a non-CSV format, before the rows are converted
+	var err error
+	if reader.format, err = DetectFormat(cfg.InputFormat, slices); err != nil {
+		return nil, err
+	}
+	if stream != nil && reader.format != config.FormatCSV {
+		return nil, kbc.UserErrorf(`the input format "%s" cannot be read from the stream, use "csv"`, reader.format)
+	}
+	if reader.format != config.FormatCSV {
+		// Columns are read before the rows, and the parquet format requires random access, so a named pipe cannot be used
+		for _, slice := range slices {
+			if stat, err := os.Stat(slice); err == nil && !stat.Mode().IsRegular() {
+				return nil, kbc.UserErrorf(`the input format "%s" cannot be read from "%s", it is not a regular file, use "csv"`, reader.format, slice)
+			}
+		}
+	}
+	switch reader.format {
+	case config.FormatParquet:
+		reader.columns, err = reader.parquetColumns()
+	case config.FormatJSONL:
+		reader.columns, err = reader.jsonlColumns()
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	// Create pipe to merge content of the slices
 	pipeOut, pipeIn := io.Pipe()
 	reader.closers.Append(pipeOut.Close)
@@ -95,7 +149,18 @@ func (r *Reader) Slices() uint32 {
 	return uint32(len(r.slices))
 }
 
+// Format of the input table.
+func (r *Reader) Format() config.Format {
+	return r.format
+}
+
+// Header returns columns from the first row of the CSV table, or columns of the other formats.
+// The columns of the other formats are not part of the rows.
 func (r *Reader) Header() ([]string, error) {
+	if r.format != config.FormatCSV {
+		return r.columns, nil
+	}
+
 	// The method can be used only with non-sliced input
 	if r.sliced {
 		return nil, fmt.Errorf(
@@ -156,7 +221,7 @@ func (r *Reader) readSlicesTo(pipeIn *io.PipeWriter) {
 	// The specified number of slices will be opened ahead and the beginning of the slice will be preloaded.
 	// This ensures a smooth transition between slices, without losing performance.
 	readers := make(chan *sliceReadCloser, r.config.AheadSlices)
-	grp, ctx := errgroup.WithContext(context.Background())
+	grp, ctx := errgroup.WithContext(r.ctx)
 
 	// Open multiple readers in background
 	grp.Go(func() error {
@@ -186,7 +251,7 @@ func (r *Reader) readSlicesTo(pipeIn *io.PipeWriter) {
 			case <-ctx.Done():
 				return nil
 			default:
-				_, readErr := io.Copy(pipeIn, sliceReader)
+				readErr := r.copySlice(pipeIn, sliceReader)
 				closeErr := sliceReader.Close()
 				if readErr != nil {
 					return readErr
@@ -203,34 +268,104 @@ func (r *Reader) readSlicesTo(pipeIn *io.PipeWriter) {
 	_ = pipeIn.CloseWithError(err)
 }
 
+// copySlice writes CSV rows of the slice to the pipe, rows of other formats are converted.
+func (r *Reader) copySlice(out io.Writer, in *sliceReadCloser) error {
+	switch r.format {
+	case config.FormatParquet:
+		return r.parquetToCSV(out, in.readerAt, in.path)
+	case config.FormatJSONL:
+		return r.jsonlToCSV(out, in, in.path)
+	default:
+		_, err := io.Copy(out, in)
+		return err
+	}
+}
+
+// openSlice opens the slice, the span covers opening and reading of the slice, it ends when the slice is closed.
 func (r *Reader) openSlice(path string) (*sliceReadCloser, error) {
-	out := &sliceReadCloser{}
+	_, span := tracing.Start(r.ctx, "read input slice", attribute.String("path", path))
+	out, err := r.openSliceReader(path)
+	if err != nil {
+		tracing.EndWithError(span, err)
+		return nil, err
+	}
+	out.span = span
+	return out, nil
+}
+
+func (r *Reader) openSliceReader(path string) (*sliceReadCloser, error) {
+	if r.stream != nil {
+		return r.openStream()
+	}
+
+	out := &sliceReadCloser{path: path}
 
 	// Open the file
-	if file, err := os.OpenFile(path, os.O_RDONLY, 0); err == nil {
-		out.Reader = file
-		out.Closers.Append(file.Close)
-	} else {
+	file, err := os.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
 		return nil, err
 	}
+	out.Reader = file
+	out.Closers.Append(file.Close)
+
+	// Parquet is read randomly, without decompression and read ahead
+	if r.format == config.FormatParquet {
+		if strings.HasSuffix(path, kbc.GzipFileExtension) {
+			_ = out.Close()
+			return nil, kbc.UserErrorf(`the parquet file "%s" cannot be compressed by gzip`, filepath.Base(path))
+		}
+		out.readerAt = r.progress.NewReaderAtMeter(file)
+		return out, nil
+	}
 
 	// Measure the reading progress of each slice
 	out.Reader = r.progress.NewMeter(out.Reader)
 
 	// Add decompression
 	if strings.HasSuffix(path, kbc.GzipFileExtension) {
-		if gzipReader, err := r.gzipReaders.ReaderFrom(out.Reader); err == nil {
-			out.Reader = gzipReader
-			out.Closers.
-				Append(func() error {
-					defer r.gzipReaders.Put(gzipReader)
-					return gzipReader.Close()
-				})
-		} else {
+		if err := r.addDecompression(out); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.addReadAhead(out)
+}
+
+// openStream wraps the stream, it is not closed by the Reader.
+func (r *Reader) openStream() (*sliceReadCloser, error) {
+	out := &sliceReadCloser{path: kbc.StdStreamPath}
+
+	// Measure the reading progress
+	buffered := bufio.NewReader(r.progress.NewMeter(r.stream))
+	out.Reader = buffered
+
+	// Detect compression by the magic bytes
+	if magic, err := buffered.Peek(len(gzipMagicBytes)); err == nil && string(magic) == gzipMagicBytes {
+		if err := r.addDecompression(out); err != nil {
 			return nil, err
 		}
+	} else if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
 	}
 
+	return r.addReadAhead(out)
+}
+
+func (r *Reader) addDecompression(out *sliceReadCloser) error {
+	gzipReader, err := r.gzipReaders.ReaderFrom(out.Reader)
+	if err != nil {
+		return err
+	}
+	out.Reader = gzipReader
+	out.Closers.
+		Append(func() error {
+			defer r.gzipReaders.Put(gzipReader)
+			return gzipReader.Close()
+		})
+	return nil
+}
+
+func (r *Reader) addReadAhead(out *sliceReadCloser) (*sliceReadCloser, error) {
 	// Add read ahead buffer
 	if r.config.AheadBlocks != 0 {
 		buffers := r.aheadBuffers.Get()
@@ -248,3 +383,34 @@ func (r *Reader) openSlice(path string) (*sliceReadCloser, error) {
 
 	return out, nil
 }
+
+// openSliceFile opens the slice for a columns discovery, without the progress meter and read ahead.
+func (r *Reader) openSliceFile(path string) (*sliceReadCloser, error) {
+	out := &sliceReadCloser{path: path}
+
+	// Open the file
+	file, err := os.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	out.Reader = file
+	out.Closers.Append(file.Close)
+
+	// Add decompression
+	if strings.HasSuffix(path, kbc.GzipFileExtension) {
+		if err := r.addDecompression(out); err != nil {
+			_ = out.Close()
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+func (s *sliceReadCloser) Close() error {
+	err := s.Closers.Close()
+	if s.span != nil {
+		tracing.EndWithError(s.span, err)
+	}
+	return err
+}
@@ -1,8 +1,13 @@
 package rowsreader
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/benbjohnson/clock"
@@ -36,7 +41,7 @@ func TestReadHeader(t *testing.T) {
 	_, testFile, _, _ := runtime.Caller(0)
 	rootDir := filepath.Dir(testFile)
 
-	csvReader, err := NewFileReader(newTestProgressLogger(), config.Default(), filepath.Join(rootDir, "fixtures", "two_rows.csv"), ',', '"')
+	csvReader, err := NewFileReader(context.Background(), newTestProgressLogger(), config.Default(), filepath.Join(rootDir, "fixtures", "two_rows.csv"), ',', '"')
 	require.NoError(t, err)
 
 	header, err := csvReader.Header()
@@ -50,7 +55,7 @@ func TestReadHeaderCannotParse(t *testing.T) {
 	_, testFile, _, _ := runtime.Caller(0)
 	rootDir := filepath.Dir(testFile)
 
-	csvReader, err := NewFileReader(newTestProgressLogger(), config.Default(), filepath.Join(rootDir, "fixtures", "bad_header.csv"), ',', '"')
+	csvReader, err := NewFileReader(context.Background(), newTestProgressLogger(), config.Default(), filepath.Join(rootDir, "fixtures", "bad_header.csv"), ',', '"')
 	require.NoError(t, err)
 
 	_, err = csvReader.Header()
@@ -65,7 +70,7 @@ func TestReadHeaderRowAlreadyRead(t *testing.T) {
 	_, testFile, _, _ := runtime.Caller(0)
 	rootDir := filepath.Dir(testFile)
 
-	csvReader, err := NewFileReader(newTestProgressLogger(), config.Default(), filepath.Join(rootDir, "fixtures", "two_rows.csv"), ',', '"')
+	csvReader, err := NewFileReader(context.Background(), newTestProgressLogger(), config.Default(), filepath.Join(rootDir, "fixtures", "two_rows.csv"), ',', '"')
 	require.NoError(t, err)
 
 	csvReader.Read()
@@ -82,7 +87,7 @@ func TestReadHeaderEmptyFile(t *testing.T) {
 	_, testFile, _, _ := runtime.Caller(0)
 	rootDir := filepath.Dir(testFile)
 
-	csvReader, err := NewFileReader(newTestProgressLogger(), config.Default(), filepath.Join(rootDir, "fixtures", "empty.csv"), ',', '"')
+	csvReader, err := NewFileReader(context.Background(), newTestProgressLogger(), config.Default(), filepath.Join(rootDir, "fixtures", "empty.csv"), ',', '"')
 	require.NoError(t, err)
 
 	_, err = csvReader.Header()
@@ -101,7 +106,7 @@ func TestReadHeaderSlicedFile(t *testing.T) {
 	slices, err := kbc.FindSlices(path)
 	require.NoError(t, err)
 
-	csvReader, err := NewSlicesReader(newTestProgressLogger(), config.Default(), path, slices, ',', '"')
+	csvReader, err := NewSlicesReader(context.Background(), newTestProgressLogger(), config.Default(), path, slices, ',', '"')
 	require.NoError(t, err)
 
 	_, err = csvReader.Header()
@@ -118,7 +123,7 @@ func TestReadCSVFile(t *testing.T) {
 	for _, testData := range getReadCsvTestData() {
 		var rows []string
 
-		csvReader, err := NewFileReader(newTestProgressLogger(), config.Default(), filepath.Join(rootDir, "fixtures", testData.csvPath), ',', '"')
+		csvReader, err := NewFileReader(context.Background(), newTestProgressLogger(), config.Default(), filepath.Join(rootDir, "fixtures", testData.csvPath), ',', '"')
 		require.NoError(t, err)
 
 		for csvReader.Read() {
@@ -129,6 +134,45 @@ func TestReadCSVFile(t *testing.T) {
 	}
 }
 
+func TestReadStream(t *testing.T) {
+	t.Parallel()
+
+	content := "\"id\",\"name\"\n\"1\",\"foo\"\n\"2\",\"bar\nbaz\"\n"
+	var compressed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressed)
+	_, err := gzipWriter.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, gzipWriter.Close())
+
+	// Compression is detected by the magic bytes
+	for _, stream := range []io.Reader{strings.NewReader(content), &compressed} {
+		csvReader, err := NewStreamReader(context.Background(), newTestProgressLogger(), config.Default(), stream, ',', '"')
+		require.NoError(t, err)
+
+		header, err := csvReader.Header()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"id", "name"}, header)
+
+		var rows []string
+		for csvReader.Read() {
+			rows = append(rows, string(csvReader.Bytes()))
+		}
+		require.NoError(t, csvReader.Close())
+		assert.Equal(t, []string{"\"1\",\"foo\"\n", "\"2\",\"bar\nbaz\"\n"}, rows)
+	}
+}
+
+func TestReadStream_NotCSV(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.InputFormat = config.FormatJSONL
+	_, err := NewStreamReader(context.Background(), newTestProgressLogger(), cfg, strings.NewReader(""), ',', '"')
+	if assert.Error(t, err) {
+		assert.Equal(t, `the input format "jsonl" cannot be read from the stream, use "csv"`, err.Error())
+	}
+}
+
 // Test for splitting function.
 func TestSplitRowsFunc(t *testing.T) {
 	t.Parallel()
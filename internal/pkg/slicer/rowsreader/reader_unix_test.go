@@ -0,0 +1,28 @@
+//go:build unix
+
+package rowsreader
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+)
+
+func TestReadNamedPipe_NotCSV(t *testing.T) {
+	t.Parallel()
+
+	// The named pipe is not opened, so the test cannot hang
+	path := filepath.Join(t.TempDir(), "table.jsonl")
+	require.NoError(t, unix.Mkfifo(path, 0o600))
+
+	_, err := NewFileReader(context.Background(), newTestProgressLogger(), config.Default(), path, ',', '"')
+	if assert.Error(t, err) {
+		assert.Equal(t, `the input format "jsonl" cannot be read from "`+path+`", it is not a regular file, use "csv"`, err.Error())
+	}
+}
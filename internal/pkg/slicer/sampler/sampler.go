@@ -0,0 +1,135 @@
+// Package sampler provides deterministic random sampling of rows.
+//
+// The sample is defined by a fraction of the rows or by a fixed number of rows.
+// The random generator is initialized by the configured seed,
+// so repeated runs with the same input produce the same sample.
+//
+// The fraction is applied to each row immediately.
+// The fixed number of rows is selected by the reservoir sampling,
+// the selected rows are kept in memory and written on Close, in the input order.
+// The size of the selected rows is limited by the configured SpillBufferSize, a larger sample is a user error.
+package sampler
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/c2h5oh/datasize"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/pipeline"
+	"github.com/keboola/processor-split-table/internal/pkg/utils"
+)
+
+// rowOverhead is an estimated memory usage of one row in the reservoir, without the row.
+const rowOverhead = 48
+
+// Sampler keeps only a random sample of the rows.
+type Sampler struct {
+	next      pipeline.Writer
+	random    *rand.Rand
+	fraction  float64
+	reservoir []sampledRow // nil, if the fraction is used
+	capacity  uint64
+	size      datasize.ByteSize
+	maxMemory datasize.ByteSize
+
+	inRows  uint64
+	outRows uint64
+}
+
+type sampledRow struct {
+	index uint64 // position of the row in the input, the input order is restored on Close
+	row   []byte
+}
+
+// New creates the Sampler stage, maxMemory limits the size of the reservoir.
+func New(cfg config.SampleConfig, maxMemory datasize.ByteSize, next pipeline.Writer) (*Sampler, error) {
+	if cfg.Fraction > 0 && cfg.Rows > 0 {
+		return nil, kbc.UserErrorf(`sample fraction and sample rows cannot be used together`)
+	}
+	if !cfg.Enabled() {
+		return nil, kbc.UserErrorf(`sample fraction or sample rows must be set`)
+	}
+
+	s := &Sampler{
+		next:      next,
+		random:    rand.New(rand.NewSource(cfg.Seed)), // nolint: gosec
+		fraction:  cfg.Fraction,
+		capacity:  cfg.Rows,
+		maxMemory: maxMemory,
+	}
+	if s.capacity > 0 {
+		s.reservoir = make([]sampledRow, 0, min(s.capacity, 1024))
+	}
+	return s, nil
+}
+
+func (s *Sampler) Write(row []byte) error {
+	index := s.inRows
+	s.inRows++
+
+	// Fraction
+	if s.capacity == 0 {
+		if s.random.Float64() < s.fraction {
+			s.outRows++
+			return s.next.Write(row)
+		}
+		return nil
+	}
+
+	// Reservoir, fill it with the first rows
+	if uint64(len(s.reservoir)) < s.capacity {
+		row = pipeline.CopyRow(row)
+		s.reservoir = append(s.reservoir, sampledRow{index: index, row: row})
+		s.size += datasize.ByteSize(len(row) + rowOverhead)
+		return s.checkSize()
+	}
+
+	// Reservoir, replace a random row with the probability capacity/rows
+	if j := uint64(s.random.Int63n(int64(s.inRows))); j < s.capacity {
+		s.size -= datasize.ByteSize(len(s.reservoir[j].row))
+		s.reservoir[j] = sampledRow{index: index, row: pipeline.CopyRow(row)}
+		s.size += datasize.ByteSize(len(s.reservoir[j].row))
+		return s.checkSize()
+	}
+	return nil
+}
+
+// checkSize returns a user error, if the selected rows don't fit into the memory.
+func (s *Sampler) checkSize() error {
+	if s.maxMemory > 0 && s.size > s.maxMemory {
+		return kbc.UserErrorf(
+			`the sample of %d rows exceeds the spill buffer size %s at the row %d, please reduce the sample rows or increase the spill buffer size`,
+			s.capacity, utils.RemoveSpaces(s.maxMemory.HumanReadable()), s.inRows,
+		)
+	}
+	return nil
+}
+
+// Close writes rows from the reservoir, if any, to the next stage and closes it.
+func (s *Sampler) Close() error {
+	sort.Slice(s.reservoir, func(i, j int) bool {
+		return s.reservoir[i].index < s.reservoir[j].index
+	})
+	for _, item := range s.reservoir {
+		if err := s.next.Write(item.row); err != nil {
+			return err
+		}
+		s.outRows++
+	}
+	s.reservoir = nil
+
+	return s.next.Close()
+}
+
+// InRows returns number of the rows before sampling.
+func (s *Sampler) InRows() uint64 {
+	return s.inRows
+}
+
+// OutRows returns number of the sampled rows.
+func (s *Sampler) OutRows() uint64 {
+	return s.outRows
+}
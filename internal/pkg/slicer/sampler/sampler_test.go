@@ -0,0 +1,147 @@
+package sampler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+)
+
+type rowsCollector struct {
+	rows   []string
+	closed bool
+}
+
+func (c *rowsCollector) Write(row []byte) error {
+	c.rows = append(c.rows, string(row))
+	return nil
+}
+
+func (c *rowsCollector) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestSampler_Fraction(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.SampleConfig{Fraction: 0.1, Seed: 123}
+	first := sample(t, cfg, 10000)
+	second := sample(t, cfg, 10000)
+
+	// The sample is deterministic
+	assert.Equal(t, first, second)
+	assert.InDelta(t, 1000, len(first), 100)
+	assertInputOrder(t, first)
+
+	// Different seed, different sample
+	cfg.Seed = 456
+	assert.NotEqual(t, first, sample(t, cfg, 10000))
+}
+
+func TestSampler_Rows(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.SampleConfig{Rows: 100, Seed: 123}
+	first := sample(t, cfg, 10000)
+	second := sample(t, cfg, 10000)
+
+	// The sample is deterministic
+	assert.Equal(t, first, second)
+	assert.Len(t, first, 100)
+	assertInputOrder(t, first)
+
+	// Different seed, different sample
+	cfg.Seed = 456
+	assert.NotEqual(t, first, sample(t, cfg, 10000))
+
+	// Fewer rows than the sample size, all rows are kept
+	assert.Len(t, sample(t, cfg, 50), 50)
+}
+
+func TestSampler_LastRowWithoutNewLine(t *testing.T) {
+	t.Parallel()
+
+	out := &rowsCollector{}
+	s, err := New(config.SampleConfig{Rows: 10}, 0, out)
+	require.NoError(t, err)
+	require.NoError(t, s.Write([]byte("\"1\"\n")))
+	require.NoError(t, s.Write([]byte("\"2\"")))
+	require.NoError(t, s.Close())
+
+	assert.Equal(t, []string{"\"1\"\n", "\"2\"\n"}, out.rows)
+	assert.True(t, out.closed)
+	assert.Equal(t, uint64(2), s.InRows())
+	assert.Equal(t, uint64(2), s.OutRows())
+}
+
+func TestSampler_MaxMemory(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(config.SampleConfig{Rows: 10}, 1000, &rowsCollector{})
+	require.NoError(t, err)
+
+	// The reservoir is full, replaced rows are counted by their size
+	row := []byte(strings.Repeat("a", 50) + "\n")
+	for i := 0; i < 100; i++ {
+		require.NoError(t, s.Write(row))
+	}
+
+	// Wider rows don't fit
+	err = s.Write([]byte(strings.Repeat("b", 1000) + "\n"))
+	for i := 0; err == nil && i < 1000; i++ {
+		err = s.Write([]byte(strings.Repeat("b", 1000) + "\n"))
+	}
+	require.Error(t, err)
+	assert.IsType(t, &kbc.UserError{}, err)
+	assert.Regexp(t, `^the sample of 10 rows exceeds the spill buffer size 1000B at the row \d+, please reduce the sample rows or increase the spill buffer size$`, err.Error())
+}
+
+func TestSampler_InvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(config.SampleConfig{Fraction: 0.5, Rows: 10}, 0, &rowsCollector{})
+	if assert.Error(t, err) {
+		assert.Equal(t, "sample fraction and sample rows cannot be used together", err.Error())
+	}
+
+	_, err = New(config.SampleConfig{}, 0, &rowsCollector{})
+	if assert.Error(t, err) {
+		assert.Equal(t, "sample fraction or sample rows must be set", err.Error())
+	}
+}
+
+func sample(t *testing.T, cfg config.SampleConfig, rowsCount int) []string {
+	t.Helper()
+
+	out := &rowsCollector{}
+	s, err := New(cfg, 0, out)
+	require.NoError(t, err)
+	for i := 0; i < rowsCount; i++ {
+		require.NoError(t, s.Write([]byte(fmt.Sprintf("\"%d\"\n", i))))
+	}
+	require.NoError(t, s.Close())
+
+	assert.True(t, out.closed)
+	assert.Equal(t, uint64(rowsCount), s.InRows())
+	assert.Equal(t, uint64(len(out.rows)), s.OutRows())
+	return out.rows
+}
+
+func assertInputOrder(t *testing.T, rows []string) {
+	t.Helper()
+
+	last := -1
+	for _, row := range rows {
+		var value int
+		_, err := fmt.Sscanf(row, "\"%d\"\n", &value)
+		require.NoError(t, err)
+		assert.Greater(t, value, last)
+		last = value
+	}
+}
@@ -0,0 +1,280 @@
+package slicedwriter
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/apache/arrow/go/v14/arrow/array"
+	"github.com/apache/arrow/go/v14/arrow/decimal128"
+	"github.com/apache/arrow/go/v14/arrow/memory"
+	"github.com/c2h5oh/datasize"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/columnsparser"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/typeinfer"
+)
+
+const (
+	// MaxRecordSize limits the memory used by the buffered rows, it is measured before conversion, in CSV bytes.
+	MaxRecordSize = 64 * datasize.MB
+	// maxRecordRows limits the number of rows in a record, a parquet row group or an arrow record batch.
+	maxRecordRows = 1_000_000
+)
+
+// arrowRows converts CSV rows to Arrow records, it is used by the typed output formats.
+//
+// Types are mapped:
+//   - INTEGER -> int64
+//   - NUMERIC -> decimal128 with the inferred precision and scale, so the values are not rounded
+//     or a nullable utf8, if the precision exceeds 38 digits
+//   - BOOLEAN -> bool
+//   - DATE -> date32
+//   - TIMESTAMP -> timestamp[us, UTC]
+//   - STRING -> utf8
+//
+// An empty value of a typed column is a null, an empty value of a STRING column is an empty string.
+type arrowRows struct {
+	schema    *arrow.Schema
+	columns   []typeinfer.Column
+	builder   *array.RecordBuilder
+	appenders []func(value []byte) error
+	parser    *columnsparser.Parser
+	rows      uint64
+	bytes     uint64 // CSV bytes of the buffered rows
+}
+
+func newArrowRows(columns []typeinfer.Column, delimiter, enclosure byte) *arrowRows {
+	fields := make([]arrow.Field, len(columns))
+	for i, column := range columns {
+		fields[i] = arrow.Field{Name: column.Name, Type: arrowType(column), Nullable: column.Type != typeinfer.TypeString}
+	}
+
+	r := &arrowRows{
+		schema:  arrow.NewSchema(fields, nil),
+		columns: columns,
+		parser:  columnsparser.NewParser(delimiter, enclosure),
+	}
+	r.builder = array.NewRecordBuilder(memory.DefaultAllocator, r.schema)
+	r.appenders = make([]func(value []byte) error, len(columns))
+	for i, column := range columns {
+		r.appenders[i] = appender(r.builder.Field(i), column)
+	}
+	return r
+}
+
+// Append the CSV row to the buffered record.
+func (r *arrowRows) Append(row []byte) error {
+	fields, err := r.parser.Fields(row)
+	if err != nil {
+		return err
+	}
+	if len(fields) > len(r.columns) {
+		return kbc.UserErrorf("the row has %d columns, but the table has %d columns", len(fields), len(r.columns))
+	}
+
+	for i, fn := range r.appenders {
+		// A missing value, for example in an empty row, is an empty value
+		var value []byte
+		if i < len(fields) {
+			value = fields[i]
+		}
+		if err := fn(value); err != nil {
+			return kbc.UserErrorf(`cannot convert value "%s" of the column "%s" to %s: %w`, value, r.columns[i].Name, r.columns[i].Type, err)
+		}
+	}
+
+	r.rows++
+	r.bytes += uint64(len(row))
+	return nil
+}
+
+// NewRecord returns buffered rows as a record and resets the buffer, the record must be released.
+func (r *arrowRows) NewRecord() arrow.Record {
+	r.rows = 0
+	r.bytes = 0
+	return r.builder.NewRecord()
+}
+
+func (r *arrowRows) Release() {
+	r.builder.Release()
+}
+
+// recordLimits returns the maximum size and rows of a record.
+// The limits are derived from the slice limits, so a small slice is one record.
+func (w *Writer) recordLimits() (maxBytes, maxRows uint64) {
+	maxBytes, maxRows = MaxRecordSize.Bytes(), maxRecordRows
+	switch w.config.Mode {
+	case config.ModeBytes:
+		maxBytes = min(maxBytes, w.config.BytesPerSlice.Bytes())
+	case config.ModeRows:
+		maxRows = min(maxRows, w.config.RowsPerSlice)
+	}
+	return maxBytes, maxRows
+}
+
+func arrowType(column typeinfer.Column) arrow.DataType {
+	switch column.Type {
+	case typeinfer.TypeInteger:
+		return arrow.PrimitiveTypes.Int64
+	case typeinfer.TypeNumeric:
+		if !fitsDecimal128(column) {
+			return arrow.BinaryTypes.String
+		}
+		return &arrow.Decimal128Type{Precision: int32(max(column.Precision, 1)), Scale: int32(column.Scale)}
+	case typeinfer.TypeBoolean:
+		return arrow.FixedWidthTypes.Boolean
+	case typeinfer.TypeDate:
+		return arrow.FixedWidthTypes.Date32
+	case typeinfer.TypeTimestamp:
+		return &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}
+	default:
+		return arrow.BinaryTypes.String
+	}
+}
+
+func appender(builder array.Builder, column typeinfer.Column) func(value []byte) error {
+	switch column.Type {
+	case typeinfer.TypeInteger:
+		b := builder.(*array.Int64Builder)
+		return func(value []byte) error {
+			if len(value) == 0 {
+				b.AppendNull()
+				return nil
+			}
+			v, err := strconv.ParseInt(string(value), 10, 64)
+			if err != nil {
+				return err
+			}
+			b.Append(v)
+			return nil
+		}
+	case typeinfer.TypeNumeric:
+		if !fitsDecimal128(column) {
+			b := builder.(*array.StringBuilder)
+			return func(value []byte) error {
+				if len(value) == 0 {
+					b.AppendNull()
+					return nil
+				}
+				b.BinaryBuilder.Append(value)
+				return nil
+			}
+		}
+		b := builder.(*array.Decimal128Builder)
+		return func(value []byte) error {
+			if len(value) == 0 {
+				b.AppendNull()
+				return nil
+			}
+			v, err := parseDecimal128(value, column.Precision, column.Scale)
+			if err != nil {
+				return err
+			}
+			b.Append(v)
+			return nil
+		}
+	case typeinfer.TypeBoolean:
+		b := builder.(*array.BooleanBuilder)
+		return func(value []byte) error {
+			if len(value) == 0 {
+				b.AppendNull()
+				return nil
+			}
+			switch {
+			case bytes.EqualFold(value, []byte("true")):
+				b.Append(true)
+			case bytes.EqualFold(value, []byte("false")):
+				b.Append(false)
+			default:
+				return fmt.Errorf("invalid boolean")
+			}
+			return nil
+		}
+	case typeinfer.TypeDate:
+		b := builder.(*array.Date32Builder)
+		return func(value []byte) error {
+			if len(value) == 0 {
+				b.AppendNull()
+				return nil
+			}
+			v, err := typeinfer.ParseDate(string(value))
+			if err != nil {
+				return err
+			}
+			b.Append(arrow.Date32FromTime(v))
+			return nil
+		}
+	case typeinfer.TypeTimestamp:
+		b := builder.(*array.TimestampBuilder)
+		return func(value []byte) error {
+			if len(value) == 0 {
+				b.AppendNull()
+				return nil
+			}
+			v, err := typeinfer.ParseTimestamp(string(value))
+			if err != nil {
+				return err
+			}
+			b.Append(arrow.Timestamp(v.UnixMicro()))
+			return nil
+		}
+	default:
+		b := builder.(*array.StringBuilder)
+		return func(value []byte) error {
+			b.BinaryBuilder.Append(value)
+			return nil
+		}
+	}
+}
+
+func fitsDecimal128(column typeinfer.Column) bool {
+	return column.Precision <= decimal128.MaxPrecision
+}
+
+// parseDecimal128 parses the decimal value exactly, the value is not converted to a float.
+// The value is scaled to an integer, for example "-1.5" with the scale 3 is -1500.
+func parseDecimal128(value []byte, precision, scale int) (decimal128.Num, error) {
+	digits := value
+	negative := false
+	if len(digits) > 0 && (digits[0] == '-' || digits[0] == '+') {
+		negative = digits[0] == '-'
+		digits = digits[1:]
+	}
+
+	integerPart, fractionalPart, _ := bytes.Cut(digits, []byte("."))
+	if len(integerPart) == 0 || len(integerPart)+scale > max(precision, 1) || len(fractionalPart) > scale {
+		return decimal128.Num{}, fmt.Errorf("invalid decimal(%d,%d)", precision, scale)
+	}
+
+	// Digits are accumulated in chunks, each chunk fits into uint64
+	const chunkSize = 18
+	out := decimal128.Num{}
+	appendDigits := func(digits []byte) error {
+		for len(digits) > 0 {
+			n := min(chunkSize, len(digits))
+			v, err := strconv.ParseUint(string(digits[:n]), 10, 64)
+			if err != nil {
+				return err
+			}
+			out = out.Mul(decimal128.GetScaleMultiplier(n)).Add(decimal128.FromU64(v))
+			digits = digits[n:]
+		}
+		return nil
+	}
+	if err := appendDigits(integerPart); err != nil {
+		return decimal128.Num{}, err
+	}
+	if err := appendDigits(fractionalPart); err != nil {
+		return decimal128.Num{}, err
+	}
+
+	// Pad the fractional part to the scale
+	out = out.Mul(decimal128.GetScaleMultiplier(scale - len(fractionalPart)))
+	if negative {
+		out = out.Negate()
+	}
+	return out, nil
+}
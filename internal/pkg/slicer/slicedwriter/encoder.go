@@ -0,0 +1,59 @@
+package slicedwriter
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/typeinfer"
+)
+
+// encoder converts CSV rows to the output format of a slice.
+type encoder interface {
+	Write(row []byte) error
+	// Close flushes buffered rows, the underlying writer is closed by the slice.
+	Close() error
+}
+
+// csvEncoder writes CSV rows without modification.
+type csvEncoder struct {
+	out io.Writer
+}
+
+// SetSchema defines columns of the table, it must be called before the first row is written.
+// The schema is required by the typed output formats, the CSV rows are parsed using the delimiter and the enclosure.
+func (w *Writer) SetSchema(columns []typeinfer.Column, delimiter, enclosure byte) {
+	w.columns = columns
+	w.delimiter = delimiter
+	w.enclosure = enclosure
+}
+
+func (w *Writer) newEncoder(out io.Writer) (encoder, error) {
+	switch w.config.OutputFormat {
+	case config.FormatCSV:
+		return &csvEncoder{out: out}, nil
+	case config.FormatParquet:
+		return w.newParquetEncoder(out)
+	case config.FormatJSONL:
+		return newJSONLEncoder(out, w.columns, w.delimiter, w.enclosure), nil
+	case config.FormatArrow:
+		return w.newArrowEncoder(out)
+	default:
+		return nil, fmt.Errorf(`unexpected output format "%v"`, uint(w.config.OutputFormat))
+	}
+}
+
+func (e *csvEncoder) Write(row []byte) error {
+	n, err := e.out.Write(row)
+	if err != nil {
+		return err
+	}
+	if n != len(row) {
+		return fmt.Errorf("unexpected length written, expected %d, written %d", len(row), n)
+	}
+	return nil
+}
+
+func (e *csvEncoder) Close() error {
+	return nil
+}
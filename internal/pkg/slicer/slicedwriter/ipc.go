@@ -0,0 +1,84 @@
+package slicedwriter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow/go/v14/arrow/ipc"
+	"github.com/apache/arrow/go/v14/arrow/memory"
+)
+
+// arrowEncoder writes one Arrow IPC file (Feather v2) per slice.
+// Rows are buffered and written as a record batch, when the record limit is reached, see Writer.recordLimits.
+type arrowEncoder struct {
+	rows     *arrowRows
+	writer   *ipc.FileWriter
+	maxBytes uint64
+	maxRows  uint64
+}
+
+// positionWriter provides the current position required by the IPC file writer, other seeks are not supported.
+type positionWriter struct {
+	out io.Writer
+	pos int64
+}
+
+func (w *Writer) newArrowEncoder(out io.Writer) (*arrowEncoder, error) {
+	e := &arrowEncoder{rows: newArrowRows(w.columns, w.delimiter, w.enclosure)}
+	e.maxBytes, e.maxRows = w.recordLimits()
+
+	var err error
+	e.writer, err = ipc.NewFileWriter(&positionWriter{out: out}, ipc.WithSchema(e.rows.schema), ipc.WithAllocator(memory.DefaultAllocator))
+	if err != nil {
+		e.rows.Release()
+		return nil, fmt.Errorf("cannot create arrow writer: %w", err)
+	}
+
+	return e, nil
+}
+
+func (e *arrowEncoder) Write(row []byte) error {
+	if err := e.rows.Append(row); err != nil {
+		return err
+	}
+	if e.rows.bytes >= e.maxBytes || e.rows.rows >= e.maxRows {
+		return e.flushRecord()
+	}
+	return nil
+}
+
+func (e *arrowEncoder) Close() error {
+	defer e.rows.Release()
+	if e.rows.rows > 0 {
+		if err := e.flushRecord(); err != nil {
+			return err
+		}
+	}
+	if err := e.writer.Close(); err != nil {
+		return fmt.Errorf("cannot close arrow writer: %w", err)
+	}
+	return nil
+}
+
+func (e *arrowEncoder) flushRecord() error {
+	record := e.rows.NewRecord()
+	defer record.Release()
+	if err := e.writer.Write(record); err != nil {
+		return fmt.Errorf("cannot write arrow record batch: %w", err)
+	}
+	return nil
+}
+
+func (w *positionWriter) Write(p []byte) (int, error) {
+	n, err := w.out.Write(p)
+	w.pos += int64(n)
+	return n, err
+}
+
+func (w *positionWriter) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekCurrent {
+		return 0, errors.New("only the current position can be requested")
+	}
+	return w.pos, nil
+}
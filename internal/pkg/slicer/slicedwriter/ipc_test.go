@@ -0,0 +1,162 @@
+package slicedwriter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v14/arrow/ipc"
+	"github.com/c2h5oh/datasize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/typeinfer"
+)
+
+func TestArrow(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+
+	cfg := config.Default()
+	cfg.Mode = config.ModeRows
+	cfg.RowsPerSlice = 2
+	cfg.OutputFormat = config.FormatArrow
+
+	w, err := New(cfg, 1000, tempDir)
+	require.NoError(t, err)
+	w.SetSchema([]typeinfer.Column{
+		{Name: "id", Type: typeinfer.TypeString},
+		{Name: "price", Type: typeinfer.TypeNumeric, Nullable: true, Precision: 3, Scale: 1},
+	}, ',', '"')
+
+	require.NoError(t, w.Write([]byte("\"1\",\"10.5\"\n")))
+	require.NoError(t, w.Write([]byte("\"2\",\"\"\n")))
+	require.NoError(t, w.Write([]byte("\"3\",\"-1\"")))
+	require.NoError(t, w.Close())
+
+	// Gzip is ignored, the file is read randomly
+	assert.False(t, w.GzipEnabled())
+	assert.Equal(t, uint32(2), w.Slices())
+	assert.Equal(t, "part0001.arrow", w.Parts()[0].Name)
+
+	header := "id:utf8 price:decimal(3, 1),nullable"
+	assert.Equal(t, []string{header, "1 10.5", "2 <nil>"}, readArrow(t, filepath.Join(tempDir, "part0001.arrow")))
+	assert.Equal(t, []string{header, "3 -1.0"}, readArrow(t, filepath.Join(tempDir, "part0002.arrow")))
+}
+
+func TestArrow_Decimal(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+
+	cfg := config.Default()
+	cfg.OutputFormat = config.FormatArrow
+
+	w, err := New(cfg, 1000, tempDir)
+	require.NoError(t, err)
+	w.SetSchema([]typeinfer.Column{
+		{Name: "amount", Type: typeinfer.TypeNumeric, Precision: 19, Scale: 2},
+		{Name: "wide", Type: typeinfer.TypeNumeric, Nullable: true, Precision: 40, Scale: 1},
+	}, ',', '"')
+
+	// The values cannot be represented by a float64, a value wider than decimal128 is a string
+	require.NoError(t, w.Write([]byte("\"12345678901234567.89\",\"123456789012345678901234567890123456789.5\"\n")))
+	require.NoError(t, w.Write([]byte("\"-0.01\",\"\"\n")))
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, []string{
+		"amount:decimal(19, 2),nullable wide:utf8,nullable",
+		"12345678901234567.89 123456789012345678901234567890123456789.5",
+		"-0.01 <nil>",
+	}, readArrow(t, filepath.Join(tempDir, "part0001.arrow")))
+}
+
+func TestArrow_InvalidDecimal(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.OutputFormat = config.FormatArrow
+
+	w, err := New(cfg, 1000, t.TempDir())
+	require.NoError(t, err)
+	w.SetSchema([]typeinfer.Column{{Name: "amount", Type: typeinfer.TypeNumeric, Precision: 3, Scale: 1}}, ',', '"')
+
+	err = w.Write([]byte("\"1.25\"\n"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `cannot convert value "1.25" of the column "amount" to NUMERIC: invalid decimal(3,1)`)
+	}
+}
+
+func TestArrow_RecordBatchSize(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+
+	cfg := config.Default()
+	cfg.Mode = config.ModeBytes
+	cfg.BytesPerSlice = 100 * datasize.B
+	cfg.OutputFormat = config.FormatArrow
+
+	w, err := New(cfg, 1000, tempDir)
+	require.NoError(t, err)
+	w.SetSchema([]typeinfer.Column{{Name: "id", Type: typeinfer.TypeString}}, ',', '"')
+	for i := 0; i < 20; i++ {
+		require.NoError(t, w.Write([]byte(fmt.Sprintf("\"%04d\"\n", i))))
+	}
+	require.NoError(t, w.Close())
+
+	// Each slice is one record batch, the record batch is limited by the slice size
+	assert.Equal(t, uint32(2), w.Slices())
+	for _, part := range w.Parts() {
+		f, err := os.Open(filepath.Join(tempDir, part.Name))
+		require.NoError(t, err)
+		reader, err := ipc.NewFileReader(f)
+		require.NoError(t, err)
+		assert.Equal(t, 1, reader.NumRecords())
+		record, err := reader.Record(0)
+		require.NoError(t, err)
+		assert.Equal(t, int64(part.Rows), record.NumRows())
+		require.NoError(t, reader.Close())
+		require.NoError(t, f.Close())
+	}
+}
+
+// readArrow returns the header with the fields and the rows of the arrow file, values are separated by a space.
+func readArrow(t *testing.T, path string) []string {
+	t.Helper()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	reader, err := ipc.NewFileReader(f)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	var fields []string
+	for _, field := range reader.Schema().Fields() {
+		if field.Nullable {
+			fields = append(fields, field.Name+":"+field.Type.String()+",nullable")
+		} else {
+			fields = append(fields, field.Name+":"+field.Type.String())
+		}
+	}
+
+	out := []string{strings.Join(fields, " ")}
+	for i := 0; i < reader.NumRecords(); i++ {
+		record, err := reader.Record(i)
+		require.NoError(t, err)
+		for row := 0; row < int(record.NumRows()); row++ {
+			var values []string
+			for col := 0; col < int(record.NumCols()); col++ {
+				values = append(values, formatValue(record.Column(col), row))
+			}
+			out = append(out, strings.Join(values, " "))
+		}
+	}
+	return out
+}
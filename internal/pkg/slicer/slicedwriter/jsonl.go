@@ -0,0 +1,140 @@
+package slicedwriter
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf8"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/columnsparser"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/typeinfer"
+)
+
+const jsonHex = "0123456789abcdef"
+
+// jsonlEncoder writes each CSV row as a JSON object on a single line, keys are the column names.
+//
+// Values are strings, except the inferred types:
+//   - INTEGER and NUMERIC -> number
+//   - BOOLEAN -> true/false
+//   - an empty value of a typed column -> null
+//
+// DATE and TIMESTAMP values are strings, without modification.
+type jsonlEncoder struct {
+	out     io.Writer
+	columns []typeinfer.Column
+	keys    [][]byte // encoded `"name":` prefix of each column
+	parser  *columnsparser.Parser
+	buffer  []byte
+}
+
+func newJSONLEncoder(out io.Writer, columns []typeinfer.Column, delimiter, enclosure byte) *jsonlEncoder {
+	e := &jsonlEncoder{
+		out:     out,
+		columns: columns,
+		keys:    make([][]byte, len(columns)),
+		parser:  columnsparser.NewParser(delimiter, enclosure),
+	}
+	for i, column := range columns {
+		e.keys[i] = append(appendJSONString(nil, []byte(column.Name)), ':')
+	}
+	return e
+}
+
+func (e *jsonlEncoder) Write(row []byte) error {
+	fields, err := e.parser.Fields(row)
+	if err != nil {
+		return err
+	}
+	if len(fields) > len(e.columns) {
+		return kbc.UserErrorf("the row has %d columns, but the table has %d columns", len(fields), len(e.columns))
+	}
+
+	e.buffer = append(e.buffer[:0], '{')
+	for i, column := range e.columns {
+		if i > 0 {
+			e.buffer = append(e.buffer, ',')
+		}
+		e.buffer = append(e.buffer, e.keys[i]...)
+
+		// A missing value, for example in an empty row, is an empty value
+		var value []byte
+		if i < len(fields) {
+			value = fields[i]
+		}
+		e.buffer = appendJSONValue(e.buffer, value, column.Type)
+	}
+	e.buffer = append(e.buffer, '}', '\n')
+
+	_, err = e.out.Write(e.buffer)
+	return err
+}
+
+func (e *jsonlEncoder) Close() error {
+	return nil
+}
+
+// appendJSONValue encodes the value according to the column type.
+// The types are inferred from all input values, so the values of a typed column are valid.
+func appendJSONValue(out, value []byte, t typeinfer.BaseType) []byte {
+	switch {
+	case t == typeinfer.TypeString:
+		return appendJSONString(out, value)
+	case len(value) == 0:
+		return append(out, "null"...)
+	case t == typeinfer.TypeInteger || t == typeinfer.TypeNumeric:
+		// The plus sign is not allowed in JSON
+		return append(out, bytes.TrimPrefix(value, []byte("+"))...)
+	case t == typeinfer.TypeBoolean:
+		if bytes.EqualFold(value, []byte("true")) {
+			return append(out, "true"...)
+		}
+		return append(out, "false"...)
+	default:
+		return appendJSONString(out, value)
+	}
+}
+
+// appendJSONString encodes the value as a JSON string, an invalid UTF-8 sequence is replaced by U+FFFD.
+func appendJSONString(out, value []byte) []byte {
+	out = append(out, '"')
+	start := 0
+	for i := 0; i < len(value); {
+		// Single byte characters
+		if char := value[i]; char < utf8.RuneSelf {
+			if char >= 0x20 && char != '"' && char != '\\' {
+				i++
+				continue
+			}
+			out = append(out, value[start:i]...)
+			switch char {
+			case '"', '\\':
+				out = append(out, '\\', char)
+			case '\n':
+				out = append(out, '\\', 'n')
+			case '\r':
+				out = append(out, '\\', 'r')
+			case '\t':
+				out = append(out, '\\', 't')
+			default:
+				out = append(out, '\\', 'u', '0', '0', jsonHex[char>>4], jsonHex[char&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		// Multi byte characters
+		r, size := utf8.DecodeRune(value[i:])
+		if r == utf8.RuneError && size == 1 {
+			out = append(out, value[start:i]...)
+			out = append(out, `�`...)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	out = append(out, value[start:]...)
+	return append(out, '"')
+}
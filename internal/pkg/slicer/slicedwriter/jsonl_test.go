@@ -0,0 +1,81 @@
+package slicedwriter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/typeinfer"
+)
+
+func TestJSONL(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+
+	cfg := config.Default()
+	cfg.Mode = config.ModeRows
+	cfg.RowsPerSlice = 2
+	cfg.Gzip = false
+	cfg.OutputFormat = config.FormatJSONL
+
+	w, err := New(cfg, 1000, tempDir)
+	require.NoError(t, err)
+	w.SetSchema([]typeinfer.Column{
+		{Name: "id", Type: typeinfer.TypeInteger},
+		{Name: "price", Type: typeinfer.TypeNumeric, Nullable: true},
+		{Name: "active", Type: typeinfer.TypeBoolean},
+		{Name: "day", Type: typeinfer.TypeDate},
+		{Name: "note \"x\"", Type: typeinfer.TypeString},
+	}, ',', '"')
+
+	require.NoError(t, w.Write([]byte("\"1\",\"10.5\",\"true\",\"2023-01-31\",\"a\"\n")))
+	require.NoError(t, w.Write([]byte("\"+2\",\"\",\"FALSE\",\"2023-02-01\",\"\"\n")))
+	require.NoError(t, w.Write([]byte("\"3\",\"-1\",\"true\",\"2023-02-02\",\"line1\nline2 \"\"quoted\"\" \\ \t\x01 ž\"")))
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, uint32(2), w.Slices())
+	assert.Equal(t, "part0001.jsonl", w.Parts()[0].Name)
+
+	part1, err := os.ReadFile(filepath.Join(tempDir, "part0001.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, ""+
+		`{"id":1,"price":10.5,"active":true,"day":"2023-01-31","note \"x\"":"a"}`+"\n"+
+		`{"id":2,"price":null,"active":false,"day":"2023-02-01","note \"x\"":""}`+"\n",
+		string(part1),
+	)
+
+	part2, err := os.ReadFile(filepath.Join(tempDir, "part0002.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, ""+
+		`{"id":3,"price":-1,"active":true,"day":"2023-02-02","note \"x\"":"line1\nline2 \"quoted\" \\ \t\u0001 ž"}`+"\n",
+		string(part2),
+	)
+}
+
+func TestJSONL_Gzip(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+
+	cfg := config.Default()
+	cfg.OutputFormat = config.FormatJSONL
+
+	w, err := New(cfg, 1000, tempDir)
+	require.NoError(t, err)
+	w.SetSchema([]typeinfer.Column{{Name: "id", Type: typeinfer.TypeString}}, ',', '"')
+	require.NoError(t, w.Write([]byte("\"1\"\n")))
+	require.NoError(t, w.Close())
+
+	assert.True(t, w.GzipEnabled())
+	assert.Equal(t, "part0001.jsonl.gz", w.Parts()[0].Name)
+}
+
+func TestAppendJSONString_InvalidUTF8(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, `"a�b"`, string(appendJSONString(nil, []byte("a\xffb"))))
+}
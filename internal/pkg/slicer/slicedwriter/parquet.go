@@ -0,0 +1,89 @@
+package slicedwriter
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow/go/v14/parquet"
+	"github.com/apache/arrow/go/v14/parquet/compress"
+	"github.com/apache/arrow/go/v14/parquet/pqarrow"
+
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+)
+
+// parquetEncoder writes one parquet file per slice.
+// Rows are buffered and written as a row group, when the record limit is reached, see Writer.recordLimits.
+type parquetEncoder struct {
+	rows     *arrowRows
+	writer   *pqarrow.FileWriter
+	maxBytes uint64
+	maxRows  uint64
+}
+
+func (w *Writer) newParquetEncoder(out io.Writer) (*parquetEncoder, error) {
+	codec, err := parquetCodec(w.config.ParquetCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &parquetEncoder{rows: newArrowRows(w.columns, w.delimiter, w.enclosure)}
+	e.maxBytes, e.maxRows = w.recordLimits()
+
+	props := parquet.NewWriterProperties(
+		parquet.WithCompression(codec),
+		parquet.WithMaxRowGroupLength(int64(e.maxRows)),
+		parquet.WithCreatedBy("keboola/processor-split-table"),
+	)
+	e.writer, err = pqarrow.NewFileWriter(e.rows.schema, out, props, pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema()))
+	if err != nil {
+		e.rows.Release()
+		return nil, fmt.Errorf("cannot create parquet writer: %w", err)
+	}
+
+	return e, nil
+}
+
+func (e *parquetEncoder) Write(row []byte) error {
+	if err := e.rows.Append(row); err != nil {
+		return err
+	}
+	if e.rows.bytes >= e.maxBytes || e.rows.rows >= e.maxRows {
+		return e.flushRowGroup()
+	}
+	return nil
+}
+
+func (e *parquetEncoder) Close() error {
+	defer e.rows.Release()
+	if e.rows.rows > 0 {
+		if err := e.flushRowGroup(); err != nil {
+			return err
+		}
+	}
+	if err := e.writer.Close(); err != nil {
+		return fmt.Errorf("cannot close parquet writer: %w", err)
+	}
+	return nil
+}
+
+func (e *parquetEncoder) flushRowGroup() error {
+	record := e.rows.NewRecord()
+	defer record.Release()
+	if err := e.writer.Write(record); err != nil {
+		return fmt.Errorf("cannot write parquet row group: %w", err)
+	}
+	return nil
+}
+
+func parquetCodec(compression config.Compression) (compress.Compression, error) {
+	switch compression {
+	case config.CompressionNone:
+		return compress.Codecs.Uncompressed, nil
+	case config.CompressionSnappy:
+		return compress.Codecs.Snappy, nil
+	case config.CompressionZstd:
+		return compress.Codecs.Zstd, nil
+	default:
+		return 0, fmt.Errorf(`unexpected parquet compression "%d"`, compression)
+	}
+}
@@ -0,0 +1,175 @@
+package slicedwriter
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/apache/arrow/go/v14/arrow/array"
+	"github.com/apache/arrow/go/v14/arrow/memory"
+	"github.com/apache/arrow/go/v14/parquet/file"
+	"github.com/apache/arrow/go/v14/parquet/pqarrow"
+	"github.com/c2h5oh/datasize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/typeinfer"
+)
+
+func TestParquet(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+
+	cfg := config.Default()
+	cfg.Mode = config.ModeRows
+	cfg.RowsPerSlice = 2
+	cfg.OutputFormat = config.FormatParquet
+	cfg.ParquetCompression = config.CompressionZstd
+
+	w, err := New(cfg, 1000, tempDir)
+	require.NoError(t, err)
+	w.SetSchema([]typeinfer.Column{
+		{Name: "id", Type: typeinfer.TypeInteger},
+		{Name: "price", Type: typeinfer.TypeNumeric, Nullable: true, Precision: 3, Scale: 1},
+		{Name: "active", Type: typeinfer.TypeBoolean},
+		{Name: "created", Type: typeinfer.TypeTimestamp},
+		{Name: "day", Type: typeinfer.TypeDate},
+		{Name: "note", Type: typeinfer.TypeString},
+	}, ',', '"')
+
+	require.NoError(t, w.Write([]byte("\"1\",\"10.5\",\"true\",\"2023-01-31 10:20:30\",\"2023-01-31\",\"a\"\n")))
+	require.NoError(t, w.Write([]byte("\"2\",\"\",\"FALSE\",\"2023-01-31T10:20:30+01:00\",\"2023-02-01\",\"\"\n")))
+	require.NoError(t, w.Write([]byte("\"3\",\"-1\",\"true\",\"2023-02-01\",\"2023-02-02\",\"c\"")))
+	require.NoError(t, w.Close())
+
+	// Gzip is ignored, the parquet format has its own compression
+	assert.False(t, w.GzipEnabled())
+	assert.Equal(t, uint32(2), w.Slices())
+	assert.Equal(t, "part0001.parquet", w.Parts()[0].Name)
+
+	assert.Equal(t, []string{
+		`1 10.5 true 2023-01-31 10:20:30 +0000 UTC 2023-01-31 a`,
+		`2 <nil> false 2023-01-31 09:20:30 +0000 UTC 2023-02-01 `,
+	}, readParquet(t, filepath.Join(tempDir, "part0001.parquet")))
+	assert.Equal(t, []string{
+		`3 -1.0 true 2023-02-01 00:00:00 +0000 UTC 2023-02-02 c`,
+	}, readParquet(t, filepath.Join(tempDir, "part0002.parquet")))
+}
+
+func TestParquet_InvalidValue(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.OutputFormat = config.FormatParquet
+
+	w, err := New(cfg, 1000, t.TempDir())
+	require.NoError(t, err)
+	w.SetSchema([]typeinfer.Column{{Name: "id", Type: typeinfer.TypeInteger}}, ',', '"')
+
+	err = w.Write([]byte("\"abc\"\n"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `cannot convert value "abc" of the column "id" to INTEGER`)
+	}
+}
+
+func TestParquet_Decimal(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+
+	cfg := config.Default()
+	cfg.OutputFormat = config.FormatParquet
+
+	w, err := New(cfg, 1000, tempDir)
+	require.NoError(t, err)
+	w.SetSchema([]typeinfer.Column{{Name: "amount", Type: typeinfer.TypeNumeric, Precision: 19, Scale: 2}}, ',', '"')
+
+	// The values cannot be represented by a float64
+	require.NoError(t, w.Write([]byte("\"12345678901234567.89\"\n")))
+	require.NoError(t, w.Write([]byte("\"-9007199254740993\"\n")))
+	require.NoError(t, w.Write([]byte("\"+0.1\"\n")))
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, []string{
+		"12345678901234567.89",
+		"-9007199254740993.00",
+		"0.10",
+	}, readParquet(t, filepath.Join(tempDir, "part0001.parquet")))
+}
+
+func TestParquet_RowGroupSize(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+
+	cfg := config.Default()
+	cfg.Mode = config.ModeBytes
+	cfg.BytesPerSlice = 100 * datasize.B
+	cfg.OutputFormat = config.FormatParquet
+
+	w, err := New(cfg, 1000, tempDir)
+	require.NoError(t, err)
+	w.SetSchema([]typeinfer.Column{{Name: "id", Type: typeinfer.TypeString}}, ',', '"')
+	for i := 0; i < 20; i++ {
+		require.NoError(t, w.Write([]byte(fmt.Sprintf("\"%04d\"\n", i))))
+	}
+	require.NoError(t, w.Close())
+
+	// Each slice is one row group, the row group is limited by the slice size
+	assert.Equal(t, uint32(2), w.Slices())
+	for _, part := range w.Parts() {
+		reader, err := file.OpenParquetFile(filepath.Join(tempDir, part.Name), false)
+		require.NoError(t, err)
+		assert.Equal(t, 1, reader.NumRowGroups())
+		assert.Equal(t, int64(part.Rows), reader.NumRows())
+		require.NoError(t, reader.Close())
+	}
+}
+
+// readParquet returns rows of the parquet file, values are separated by a space.
+func readParquet(t *testing.T, path string) []string {
+	t.Helper()
+
+	reader, err := file.OpenParquetFile(path, false)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	arrowReader, err := pqarrow.NewFileReader(reader, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	require.NoError(t, err)
+
+	table, err := arrowReader.ReadTable(context.Background())
+	require.NoError(t, err)
+	defer table.Release()
+
+	var out []string
+	for row := 0; row < int(table.NumRows()); row++ {
+		var values []string
+		for col := 0; col < int(table.NumCols()); col++ {
+			values = append(values, formatValue(table.Column(col).Data().Chunk(0), row))
+		}
+		out = append(out, strings.Join(values, " "))
+	}
+	return out
+}
+
+// formatValue returns the value as a string, dates and timestamps are formatted in UTC.
+func formatValue(column arrow.Array, row int) string {
+	if column.IsNull(row) {
+		return "<nil>"
+	}
+	switch c := column.(type) {
+	case *array.Date32:
+		return c.Value(row).ToTime().Format("2006-01-02")
+	case *array.Timestamp:
+		return c.Value(row).ToTime(arrow.Microsecond).String()
+	case *array.Decimal128:
+		return c.Value(row).ToString(c.DataType().(*arrow.Decimal128Type).Scale)
+	default:
+		return column.ValueStr(row)
+	}
+}
@@ -4,12 +4,17 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/c2h5oh/datasize"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/keboola/processor-split-table/internal/pkg/kbc"
 	"github.com/keboola/processor-split-table/internal/pkg/slicer/closer"
 	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/tracing"
 )
 
 // slice writes to the one slice.
@@ -22,29 +27,47 @@ type slice struct {
 	bytesFromGc datasize.ByteSize // bytes from last garbage collector run
 
 	out     io.Writer
+	encoder encoder // created on the first write, so the schema can be set after the first slice is created
 	closers closer.Closers
+
+	// Key range tracking, see Writer.TrackKeyRange
+	minKey  []string
+	lastRow []byte
+
+	// Zone map tracking, see Writer.TrackZoneMap
+	zoneMap *zoneMap
+
+	// Span of the slice, see Writer.TrackSpans
+	span trace.Span
 }
 
 func (w *Writer) newSlice(path string) (*slice, error) {
 	s := &slice{writer: w, path: path}
 
-	// Open the file for writing
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, kbc.NewFilePermissions)
-	if err != nil {
-		return nil, err
+	// Open the file for writing, STDOUT is not closed
+	var file io.Writer = os.Stdout
+	if path != kbc.StdStreamPath {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, kbc.NewFilePermissions)
+		if err != nil {
+			return nil, err
+		}
+		s.closers = append(s.closers, func() error {
+			return f.Close()
+		})
+		file = f
 	}
-	s.closers = append(s.closers, func() error {
-		return file.Close()
-	})
 
 	// Add gzip compression
 	if w.config.Gzip {
 		if gzipWriter, err := w.gzipWriters.WriterTo(file); err == nil {
-			s.out = gzipWriter
+			s.out = &timedWriter{writer: gzipWriter, owner: w}
 			s.closers.
 				Append(func() error {
 					defer w.gzipWriters.Put(gzipWriter)
-					return gzipWriter.Close()
+					start := time.Now()
+					err := gzipWriter.Close()
+					w.metrics.AddGzipDuration(time.Since(start))
+					return err
 				})
 		} else {
 			return nil, fmt.Errorf("cannot create gzip writer: %w", err)
@@ -62,12 +85,29 @@ func (w *Writer) newSlice(path string) (*slice, error) {
 }
 
 func (s *slice) Write(row []byte, rowLength uint64) error {
-	n, err := s.out.Write(row)
-	if err != nil {
+	if err := s.openEncoder(); err != nil {
+		return err
+	}
+	if err := s.encoder.Write(row); err != nil {
 		return fmt.Errorf("cannot write row to slice \"%s\": %w", s.path, err)
 	}
-	if n != int(rowLength) {
-		return fmt.Errorf("unexpected length written to \"%s\", expected %d, written %d", s.path, rowLength, n)
+	if s.writer.keyColumns != nil {
+		if s.rows == 0 {
+			minKey, err := s.writer.keyValues(row)
+			if err != nil {
+				return err
+			}
+			s.minKey = minKey
+		}
+		s.lastRow = append(s.lastRow[:0], row...)
+	}
+	if s.writer.zoneMapIndexes != nil {
+		if s.zoneMap == nil {
+			s.zoneMap = newZoneMap(len(s.writer.zoneMapIndexes))
+		}
+		if err := s.zoneMap.Add(s.writer.zoneMapParser, s.writer.zoneMapIndexes, row); err != nil {
+			return err
+		}
 	}
 	s.rows++
 	s.bytes += datasize.ByteSize(rowLength)
@@ -75,8 +115,51 @@ func (s *slice) Write(row []byte, rowLength uint64) error {
 	return nil
 }
 
-func (s *slice) Close() error {
-	return s.closers.Close()
+func (s *slice) Close() (err error) {
+	if s.span != nil {
+		defer func() {
+			s.span.SetAttributes(attribute.Int64("rows", int64(s.rows)), attribute.Int64("bytes", int64(s.bytes)))
+			tracing.EndWithError(s.span, err)
+		}()
+	}
+
+	// An empty slice must be also encoded, for example, an empty parquet file contains the schema
+	if err := s.openEncoder(); err != nil {
+		return err
+	}
+	if err := s.encoder.Close(); err != nil {
+		return fmt.Errorf("cannot close slice \"%s\": %w", s.path, err)
+	}
+
+	if err := s.closers.Close(); err != nil {
+		return err
+	}
+
+	part := Part{Name: filepath.Base(s.path), Rows: s.rows, Bytes: s.bytes.Bytes(), MinKey: s.minKey}
+	if s.writer.keyColumns != nil && s.rows > 0 {
+		maxKey, err := s.writer.keyValues(s.lastRow)
+		if err != nil {
+			return err
+		}
+		part.MaxKey = maxKey
+	}
+	if s.zoneMap != nil {
+		part.ZoneMap = s.zoneMap.Ranges(s.writer.zoneMapColumns)
+	}
+
+	s.writer.parts = append(s.writer.parts, part)
+	return nil
+}
+
+func (s *slice) startSpan() {
+	_, s.span = tracing.Start(s.writer.spanCtx, "write output part", attribute.String("path", s.path))
+}
+
+func (s *slice) openEncoder() (err error) {
+	if s.encoder == nil {
+		s.encoder, err = s.writer.newEncoder(s.out)
+	}
+	return err
 }
 
 func (s *slice) IsSpaceForNextRow(rowLength uint64) bool {
@@ -94,3 +177,21 @@ func (s *slice) IsSpaceForNextRow(rowLength uint64) bool {
 		panic(fmt.Errorf("unexpected sliced writer mode \"%v\"", s.writer.config.Mode))
 	}
 }
+
+// timedWriter measures time spent in the writes, it is used to measure the gzip compression, if the metrics are enabled.
+// The metrics of the owner are checked on each write, because the first slice is created before Writer.TrackMetrics is called.
+type timedWriter struct {
+	writer io.Writer
+	owner  *Writer
+}
+
+func (w *timedWriter) Write(p []byte) (int, error) {
+	m := w.owner.metrics
+	if m == nil {
+		return w.writer.Write(p)
+	}
+	start := time.Now()
+	n, err := w.writer.Write(p)
+	m.AddGzipDuration(time.Since(start))
+	return n, err
+}
@@ -1,18 +1,26 @@
 package slicedwriter
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"math"
 
 	"github.com/c2h5oh/datasize"
 
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/metrics"
 	"github.com/keboola/processor-split-table/internal/pkg/pool"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/columnsparser"
 	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/sorter"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/typeinfer"
 )
 
 // Writer writes CSV to a sliced table directory.
 // Each part is one file in the directory.
 // When maxRows/maxBytes is reached -> a new file/slice is created.
+// If the output path is kbc.StdStreamPath, all rows are written to STDOUT as a single stream, without slicing.
 type Writer struct {
 	config        config.Config
 	bufferWriters *pool.BufferWriterPool
@@ -22,6 +30,42 @@ type Writer struct {
 	slice         *slice
 	allRows       uint64
 	allBytes      datasize.ByteSize
+	parts         []Part
+	progress      ProgressTracker // optional, see TrackProgress
+	metrics       *metrics.Table  // optional, see TrackMetrics
+	spanCtx       context.Context // optional, see TrackSpans
+
+	// Schema, see SetSchema
+	columns   []typeinfer.Column
+	delimiter byte
+	enclosure byte
+
+	// Key range tracking, see TrackKeyRange
+	keyColumns []int
+	parser     *columnsparser.Parser
+	keyBuffer  []byte
+
+	// Zone map tracking, see TrackZoneMap
+	zoneMapColumns []string
+	zoneMapIndexes []int
+	zoneMapParser  *columnsparser.Parser
+}
+
+// ProgressTracker receives statistics of the written rows, for example the progress.Logger.
+type ProgressTracker interface {
+	AddRow()
+	SetPart(part uint32)
+}
+
+// Part describes one written slice.
+type Part struct {
+	Name   string   `json:"name"`
+	Rows   uint64   `json:"rows"`
+	Bytes  uint64   `json:"bytes"` // before compression
+	MinKey []string `json:"minKey,omitempty"`
+	MaxKey []string `json:"maxKey,omitempty"`
+	// ZoneMap contains min/max values of the configured columns, see TrackZoneMap.
+	ZoneMap []ColumnRange `json:"zoneMap,omitempty"`
 }
 
 func New(cfg config.Config, totalInputSize datasize.ByteSize, outPath string) (*Writer, error) {
@@ -38,6 +82,11 @@ func New(cfg config.Config, totalInputSize datasize.ByteSize, outPath string) (*
 		cfg.NumberOfSlices = 0 // disabled
 	}
 
+	// Parquet has its own compression, Arrow files are read randomly, so they cannot be compressed by gzip
+	if cfg.OutputFormat == config.FormatParquet || cfg.OutputFormat == config.FormatArrow {
+		cfg.Gzip = false
+	}
+
 	w := &Writer{
 		config:        cfg,
 		bufferWriters: pool.BufferedWriters(cfg.BufferSize),
@@ -53,11 +102,23 @@ func New(cfg config.Config, totalInputSize datasize.ByteSize, outPath string) (*
 	return w, nil
 }
 
+// TrackKeyRange enables recording of the first and the last key of each part.
+// Rows must be sorted by the key columns, rows with the same key are never split into two parts,
+// so each part covers a disjoint key range.
+func (w *Writer) TrackKeyRange(keyColumns []int, delimiter, enclosure byte) {
+	w.keyColumns = keyColumns
+	w.parser = columnsparser.NewParser(delimiter, enclosure)
+}
+
 func (w *Writer) Write(row []byte) error {
 	rowLength := uint64(len(row))
 	if !w.IsSpaceForNextRowInSlice(rowLength) {
-		if err := w.createNextSlice(); err != nil {
+		if sameKey, err := w.hasSameKeyAsLastRow(row); err != nil {
 			return err
+		} else if !sameKey {
+			if err := w.createNextSlice(); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -67,6 +128,10 @@ func (w *Writer) Write(row []byte) error {
 
 	w.allRows++
 	w.allBytes += datasize.ByteSize(rowLength)
+	w.metrics.AddRow()
+	if w.progress != nil {
+		w.progress.AddRow()
+	}
 	return nil
 }
 
@@ -75,6 +140,11 @@ func (w *Writer) Close() error {
 }
 
 func (w *Writer) IsSpaceForNextRowInSlice(rowLength uint64) bool {
+	// Single stream, do not slice
+	if w.outPath == kbc.StdStreamPath {
+		return true
+	}
+
 	// Last slice, do not overflow
 	if w.config.NumberOfSlices > 0 && w.config.NumberOfSlices == w.sliceNumber {
 		return true
@@ -83,6 +153,31 @@ func (w *Writer) IsSpaceForNextRowInSlice(rowLength uint64) bool {
 	return w.slice.IsSpaceForNextRow(rowLength)
 }
 
+// TrackProgress enables reporting of the written rows and the current part to the tracker.
+// The current part is reported immediately.
+func (w *Writer) TrackProgress(tracker ProgressTracker) {
+	w.progress = tracker
+	tracker.SetPart(w.sliceNumber)
+}
+
+// TrackMetrics enables counting of the written rows, the created slices and the gzip time in the metrics.
+// The already created slices are counted immediately.
+func (w *Writer) TrackMetrics(m *metrics.Table) {
+	w.metrics = m
+	for i := uint32(0); i < w.sliceNumber; i++ {
+		m.AddSlice()
+	}
+}
+
+// TrackSpans enables a span per output part, the spans are children of the span in the context.
+// The span of the already created slice starts immediately.
+func (w *Writer) TrackSpans(ctx context.Context) {
+	w.spanCtx = ctx
+	if w.slice != nil {
+		w.slice.startSpan()
+	}
+}
+
 func (w *Writer) GzipEnabled() bool {
 	return w.config.Gzip
 }
@@ -99,6 +194,47 @@ func (w *Writer) AlLBytes() datasize.ByteSize {
 	return w.allBytes
 }
 
+// Parts returns description of the closed slices.
+func (w *Writer) Parts() []Part {
+	return w.parts
+}
+
+func (w *Writer) hasSameKeyAsLastRow(row []byte) (bool, error) {
+	if w.keyColumns == nil || w.slice.rows == 0 {
+		return false, nil
+	}
+
+	fields, err := w.parser.Fields(row)
+	if err != nil {
+		return false, err
+	}
+	w.keyBuffer = sorter.AppendKey(w.keyBuffer[:0], fields, w.keyColumns)
+	keyLength := len(w.keyBuffer)
+
+	fields, err = w.parser.Fields(w.slice.lastRow)
+	if err != nil {
+		return false, err
+	}
+	w.keyBuffer = sorter.AppendKey(w.keyBuffer, fields, w.keyColumns)
+
+	return bytes.Equal(w.keyBuffer[:keyLength], w.keyBuffer[keyLength:]), nil
+}
+
+func (w *Writer) keyValues(row []byte) ([]string, error) {
+	fields, err := w.parser.Fields(row)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]string, len(w.keyColumns))
+	for i, index := range w.keyColumns {
+		if index < len(fields) {
+			out[i] = string(fields[index])
+		}
+	}
+	return out, nil
+}
+
 func (w *Writer) createNextSlice() error {
 	if w.slice != nil {
 		if err := w.slice.Close(); err != nil {
@@ -107,7 +243,10 @@ func (w *Writer) createNextSlice() error {
 	}
 
 	w.sliceNumber++
-	path := getSlicePath(w.outPath, w.sliceNumber, w.config.Gzip)
+	path := kbc.StdStreamPath
+	if w.outPath != kbc.StdStreamPath {
+		path = getSlicePath(w.outPath, w.sliceNumber, w.config.OutputFormat, w.config.Gzip)
+	}
 
 	s, err := w.newSlice(path)
 	if err != nil {
@@ -115,11 +254,18 @@ func (w *Writer) createNextSlice() error {
 	}
 
 	w.slice = s
+	w.metrics.AddSlice()
+	if w.spanCtx != nil {
+		s.startSpan()
+	}
+	if w.progress != nil {
+		w.progress.SetPart(w.sliceNumber)
+	}
 	return nil
 }
 
-func getSlicePath(dirPath string, sliceNumber uint32, gzip bool) string {
-	path := dirPath + "/part" + fmt.Sprintf("%04d", sliceNumber)
+func getSlicePath(dirPath string, sliceNumber uint32, format config.Format, gzip bool) string {
+	path := dirPath + "/part" + fmt.Sprintf("%04d", sliceNumber) + format.Extension()
 	if gzip {
 		path += ".gz"
 	}
@@ -1,14 +1,19 @@
 package slicedwriter
 
 import (
+	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/c2h5oh/datasize"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/keboola/processor-split-table/internal/pkg/metrics"
 	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
 	"github.com/keboola/processor-split-table/internal/pkg/utils"
 )
@@ -342,3 +347,149 @@ func getReadCsvTestData() []testData {
 		},
 	}
 }
+
+func TestKeyRange(t *testing.T) {
+	t.Parallel()
+
+	// Create temp dir
+	tempDir := t.TempDir()
+
+	// Config
+	cfg := config.Config{
+		Mode:         config.ModeRows,
+		RowsPerSlice: 2,
+	}
+
+	// Create writer
+	w, err := New(cfg, 1000, tempDir)
+	require.NoError(t, err)
+	w.TrackKeyRange([]int{1}, ',', '"')
+
+	// Rows with the same key are not split
+	assert.NoError(t, w.Write([]byte("\"a\",\"1\"\n")))
+	assert.NoError(t, w.Write([]byte("\"b\",\"2\"\n")))
+	assert.NoError(t, w.Write([]byte("\"c\",\"2\"\n"))) // <<<<<< same key, slice overflow
+	assert.Equal(t, uint32(1), w.sliceNumber)
+	assert.NoError(t, w.Write([]byte("\"d\",\"3\"\n")))
+	assert.Equal(t, uint32(2), w.sliceNumber)
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, []Part{
+		{Name: "part0001", Rows: 3, Bytes: 24, MinKey: []string{"1"}, MaxKey: []string{"2"}},
+		{Name: "part0002", Rows: 1, Bytes: 8, MinKey: []string{"3"}, MaxKey: []string{"3"}},
+	}, w.Parts())
+}
+
+func TestZoneMap(t *testing.T) {
+	t.Parallel()
+
+	// Create temp dir
+	tempDir := t.TempDir()
+
+	// Config
+	cfg := config.Config{
+		Mode:         config.ModeRows,
+		RowsPerSlice: 2,
+	}
+
+	// Create writer
+	w, err := New(cfg, 1000, tempDir)
+	require.NoError(t, err)
+	w.TrackZoneMap([]string{"price", "name"}, []int{1, 0}, ',', '"')
+
+	// Rows don't have to be sorted
+	assert.NoError(t, w.Write([]byte("\"b\",\"10\"\n")))
+	assert.NoError(t, w.Write([]byte("\"a\",\"9\"\n")))
+	assert.NoError(t, w.Write([]byte("\"c\",\"\"\n")))
+	assert.NoError(t, w.Write([]byte("\"\"\n"))) // missing value is a null
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, []Part{
+		{
+			Name: "part0001", Rows: 2, Bytes: 17,
+			ZoneMap: []ColumnRange{
+				// Numbers are compared as numbers
+				{Column: "price", Min: "9", Max: "10"},
+				{Column: "name", Min: "a", Max: "b"},
+			},
+		},
+		{
+			Name: "part0002", Rows: 2, Bytes: 10,
+			ZoneMap: []ColumnRange{
+				{Column: "price", Nulls: 2},
+				{Column: "name", Min: "c", Max: "c", Nulls: 1},
+			},
+		},
+	}, w.Parts())
+}
+
+func TestTrackProgress(t *testing.T) {
+	t.Parallel()
+
+	// Create temp dir
+	tempDir := t.TempDir()
+
+	// Config
+	cfg := config.Config{
+		Mode:         config.ModeRows,
+		RowsPerSlice: 2,
+	}
+
+	// Create writer
+	w, err := New(cfg, 1000, tempDir)
+	require.NoError(t, err)
+
+	// The current part is reported immediately
+	tracker := &progressTracker{}
+	w.TrackProgress(tracker)
+	assert.Equal(t, uint32(1), tracker.part)
+
+	assert.NoError(t, w.Write([]byte("\"1bc\",\"def\"\n")))
+	assert.NoError(t, w.Write([]byte("\"2bc\",\"def\"\n")))
+	assert.NoError(t, w.Write([]byte("\"3bc\",\"def\"\n")))
+	assert.NoError(t, w.Close())
+	assert.Equal(t, 3, tracker.rows)
+	assert.Equal(t, uint32(2), tracker.part)
+}
+
+func TestTrackMetrics_FirstSlice(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.Mode = config.ModeRows
+
+	// The first slice is created before the metrics are tracked
+	w, err := New(cfg, 1000, t.TempDir())
+	require.NoError(t, err)
+	m := metrics.New()
+	w.TrackMetrics(m.Table("my-table"))
+
+	// Writes to the gzip writer of the first slice are measured, before it is closed
+	row := []byte(strings.Repeat("abc,", 1000) + "\n")
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, w.Write(row))
+	}
+	path := filepath.Join(t.TempDir(), "slicer.prom")
+	require.NoError(t, m.WriteTextfile(path))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	match := regexp.MustCompile(`slicer_gzip_seconds_total\{table="my-table"\} (\S+)`).FindStringSubmatch(string(content))
+	require.Len(t, match, 2)
+	seconds, err := strconv.ParseFloat(match[1], 64)
+	require.NoError(t, err)
+	assert.Greater(t, seconds, 0.0)
+	require.NoError(t, w.Close())
+}
+
+type progressTracker struct {
+	rows int
+	part uint32
+}
+
+func (t *progressTracker) AddRow() {
+	t.rows++
+}
+
+func (t *progressTracker) SetPart(part uint32) {
+	t.part = part
+}
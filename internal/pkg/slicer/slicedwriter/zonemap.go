@@ -0,0 +1,84 @@
+package slicedwriter
+
+import (
+	"bytes"
+
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/columnsparser"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/sorter"
+)
+
+// ColumnRange is the zone map of one column in a part, Min and Max are empty, if all values are null.
+// Values are compared in the same natural order as the sort keys, see sorter.AppendKey.
+type ColumnRange struct {
+	Column string `json:"column"`
+	Min    string `json:"min,omitempty"`
+	Max    string `json:"max,omitempty"`
+	Nulls  uint64 `json:"nulls"`
+}
+
+// zoneMap tracks min/max values of the configured columns in one part.
+type zoneMap struct {
+	columns []zoneMapColumn
+	value   []byte // encoded value buffer
+}
+
+type zoneMapColumn struct {
+	min    []byte
+	max    []byte
+	minKey []byte // encoded min value
+	maxKey []byte // encoded max value
+	nulls  uint64
+}
+
+// TrackZoneMap enables recording of the min/max values of the columns in each part.
+// Unlike TrackKeyRange, rows don't have to be sorted, but the ranges of the parts can overlap.
+func (w *Writer) TrackZoneMap(columns []string, columnIndexes []int, delimiter, enclosure byte) {
+	w.zoneMapColumns = columns
+	w.zoneMapIndexes = columnIndexes
+	w.zoneMapParser = columnsparser.NewParser(delimiter, enclosure)
+}
+
+func newZoneMap(columnsCount int) *zoneMap {
+	return &zoneMap{columns: make([]zoneMapColumn, columnsCount)}
+}
+
+func (z *zoneMap) Add(parser *columnsparser.Parser, indexes []int, row []byte) error {
+	fields, err := parser.Fields(row)
+	if err != nil {
+		return err
+	}
+
+	for i, index := range indexes {
+		column := &z.columns[i]
+
+		// A missing value, for example in an empty row, is a null
+		var value []byte
+		if index < len(fields) {
+			value = fields[index]
+		}
+		if len(value) == 0 {
+			column.nulls++
+			continue
+		}
+
+		z.value = sorter.AppendValue(z.value[:0], value)
+		if column.minKey == nil || bytes.Compare(z.value, column.minKey) < 0 {
+			column.minKey = append(column.minKey[:0], z.value...)
+			column.min = append(column.min[:0], value...)
+		}
+		if column.maxKey == nil || bytes.Compare(z.value, column.maxKey) > 0 {
+			column.maxKey = append(column.maxKey[:0], z.value...)
+			column.max = append(column.max[:0], value...)
+		}
+	}
+
+	return nil
+}
+
+func (z *zoneMap) Ranges(names []string) []ColumnRange {
+	out := make([]ColumnRange, len(z.columns))
+	for i, column := range z.columns {
+		out[i] = ColumnRange{Column: names[i], Min: string(column.min), Max: string(column.max), Nulls: column.nulls}
+	}
+	return out
+}
@@ -2,54 +2,146 @@
 package slicer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
 
 	"github.com/benbjohnson/clock"
 	"github.com/c2h5oh/datasize"
 	"github.com/dustin/go-humanize"
 	"github.com/go-playground/validator/v10"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/keboola/processor-split-table/internal/pkg/kbc"
 	"github.com/keboola/processor-split-table/internal/pkg/log"
 	manifestPkg "github.com/keboola/processor-split-table/internal/pkg/manifest"
+	"github.com/keboola/processor-split-table/internal/pkg/metrics"
 	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/dedup"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/memplan"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/pipeline"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/profile"
 	"github.com/keboola/processor-split-table/internal/pkg/slicer/rowsreader"
 	"github.com/keboola/processor-split-table/internal/pkg/slicer/rowsreader/progress"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/sampler"
 	"github.com/keboola/processor-split-table/internal/pkg/slicer/slicedwriter"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/sorter"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/typeinfer"
+	"github.com/keboola/processor-split-table/internal/pkg/tracing"
 	"github.com/keboola/processor-split-table/internal/pkg/utils"
 )
 
 type Table struct {
 	config.Config        `json:"config" mapstructure:",squash"`
-	Name                 string `validate:"required" json:"name"  mapstructure:"table-name"`
-	InPath               string `validate:"required"  json:"inPath" mapstructure:"table-input-path"`
-	InManifestPath       string `json:"inManifestPath"  mapstructure:"table-input-manifest-path"`
-	InManifestMustExists bool   `json:"-" mapstructure:"-"` // true in CLI, false in processor
-	OutPath              string `validate:"required" json:"outPath" mapstructure:"table-output-path"`
-	OutManifestPath      string `validate:"required" json:"outManifestPath" mapstructure:"table-output-manifest-path"`
-	InputSizeLowExitCode uint32 `json:"-"  mapstructure:"input-size-low-exit-code" validate:"max=255"`
+	Name                 string               `validate:"required" json:"name"  mapstructure:"table-name"`
+	InPath               string               `validate:"required"  json:"inPath" mapstructure:"table-input-path"`
+	InManifestPath       string               `json:"inManifestPath"  mapstructure:"table-input-manifest-path"`
+	InManifestMustExists bool                 `json:"-" mapstructure:"-"` // true in CLI, false in processor
+	OutPath              string               `validate:"required" json:"outPath" mapstructure:"table-output-path"`
+	OutManifestPath      string               `validate:"required" json:"outManifestPath" mapstructure:"table-output-manifest-path"`
+	OutPartsMetadataPath string               `json:"outPartsMetadataPath" mapstructure:"table-output-parts-metadata-path"` // optional
+	OutProfilePath       string               `json:"outProfilePath" mapstructure:"table-output-profile-path"`              // optional
+	ExpectedInputSize    datasize.ByteSize    `json:"expectedInputSize" mapstructure:"expected-input-size"`                 // optional, used if the input size is unknown
+	OutResultPath        string               `json:"outResultPath" mapstructure:"result-file"`                             // optional
+	InputSizeLowExitCode uint32               `json:"-"  mapstructure:"input-size-low-exit-code" validate:"max=255"`
+	Metrics              *metrics.Metrics     `json:"-" mapstructure:"-"` // optional, set by CLI
+	TracerProvider       trace.TracerProvider `json:"-" mapstructure:"-"` // optional, set by CLI
+	MemoryBudget         datasize.ByteSize    `json:"-" mapstructure:"-"` // optional, set by SliceTables, GOMEMLIMIT by default
+	CPUBudget            int                  `json:"-" mapstructure:"-"` // optional, set by SliceTables, GOMAXPROCS by default
 }
 
+// SliceTable slices the input table according to the configuration.
+// The Result is written to the Table.OutResultPath, if the path is set, also if the slicing failed.
 func SliceTable(logger log.Logger, table Table) (err error) {
+	start := time.Now()
+	logger = log.With(logger, "table", table.Name)
+	result := &Result{Name: table.Name, Status: ResultFailed, Manifest: ManifestNone}
+	ctx, span := tracing.StartRoot(table.TracerProvider, "slice table", attribute.String("table", table.Name))
+	err = sliceTable(ctx, logger, table, result)
+	span.SetAttributes(attribute.String("status", result.Status), attribute.Int64("rows", int64(result.Rows)))
+	tracing.EndWithError(span, err)
+
+	if table.OutResultPath != "" {
+		result.setDuration(time.Since(start))
+		var exitCodeErr *kbc.ExitCodeError
+		if err != nil && !errors.As(err, &exitCodeErr) {
+			result.Error = err.Error()
+		}
+		if resultErr := writeResult(table, result); resultErr != nil && err == nil {
+			err = resultErr
+		}
+	}
+
+	return err
+}
+
+// memoryBudget returns the memory limit of the table.
+func (t Table) memoryBudget() datasize.ByteSize {
+	if t.MemoryBudget > 0 {
+		return t.MemoryBudget
+	}
+	return datasize.ByteSize(debug.SetMemoryLimit(-1))
+}
+
+// cpuBudget returns the number of CPU threads of the table.
+func (t Table) cpuBudget() int {
+	if t.CPUBudget > 0 {
+		return t.CPUBudget
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// processed returns true, if the rows are processed by a stage, for example, to change them or to compute the profile,
+// or the metadata of the output slices are written, so the table cannot be copied, even if it is smaller than the InputSizeThreshold.
+func (t Table) processed() bool {
+	return t.Deduplication != config.DeduplicationNone || len(t.SortBy) > 0 || t.Sample.Enabled() || t.InferTypes ||
+		t.OutProfilePath != "" || t.OutPartsMetadataPath != "" || len(t.ZoneMapColumns) > 0
+}
+
+func sliceTable(ctx context.Context, logger log.Logger, table Table, result *Result) (err error) {
 	// Validate
 	val := validator.New()
 	if err := val.Struct(table); err != nil {
 		return kbc.UserErrorf(`table definition is not valid: %w`, err)
 	}
 
+	// The format of the output cannot be detected
+	if table.OutputFormat == config.FormatAuto {
+		return kbc.UserErrorf(`the "auto" format can be used only for the input`)
+	}
+
+	// Zone maps are written to the parts metadata
+	if len(table.ZoneMapColumns) > 0 && table.OutPartsMetadataPath == "" {
+		return kbc.UserErrorf(`zone map columns require the output parts metadata path`)
+	}
+
+	streamInput := table.InPath == kbc.StdStreamPath
+	streamOutput := table.OutPath == kbc.StdStreamPath
+
 	// Get input type
-	stat, err := os.Stat(table.InPath)
-	if errors.Is(err, os.ErrNotExist) {
-		return kbc.UserErrorf(`input table "%s" not found`, table.InPath)
-	} else if err != nil {
-		return err
+	var stat os.FileInfo
+	if !streamInput {
+		stat, err = os.Stat(table.InPath)
+		if errors.Is(err, os.ErrNotExist) {
+			return kbc.UserErrorf(`input table "%s" not found`, table.InPath)
+		} else if err != nil {
+			return err
+		}
 	}
-	slicedInput := stat.IsDir()
+	slicedInput := stat != nil && stat.IsDir()
+
+	// The size of STDIN, a named pipe or a device is unknown
+	unknownInputSize := streamInput || (!slicedInput && !stat.Mode().IsRegular())
 
 	// Load manifest
+	_, span := tracing.Start(ctx, "load manifest")
 	manifest, err := manifestPkg.LoadManifest(table.InManifestPath)
+	tracing.EndWithError(span, err)
 	if err != nil {
 		return err
 	}
@@ -59,60 +151,103 @@ func SliceTable(logger log.Logger, table Table) (err error) {
 		return fmt.Errorf(`manifest "%s" not found`, table.InManifestPath)
 	}
 
-	// Check manifest, if the table is sliced
-	if slicedInput && !manifest.Exists() {
-		return kbc.UserErrorf(`the manifest "%s" not found, it is required for the sliced table`, table.InManifestPath)
+	// Plan sizes of the buffer pools, so they fit into the memory limit.
+	// Columns of the profile are known, if they are defined by the manifest.
+	profiledColumns := 0
+	if table.OutProfilePath != "" {
+		profiledColumns = len(manifest.Columns())
 	}
-	if slicedInput && !manifest.HasColumns() {
-		return kbc.UserErrorf(`the manifest "%s" has no columns, columns are required for the sliced table`, table.InManifestPath)
+	plan, err := memplan.New(table.Config, profiledColumns, table.memoryBudget(), table.cpuBudget())
+	if err != nil {
+		return err
+	}
+	plan.Apply(&table.Config)
+	if plan.Changed {
+		logger.Infof("Memory plan reduced the buffers: %s.", plan)
+	} else {
+		logger.Debugf("Memory plan: %s.", plan)
 	}
 
 	// Define inputs and input size
 	var slices kbc.Slices
 	var totalInputSize, maxSliceSize datasize.ByteSize
+	inputPaths := []string{table.InPath}
 	if slicedInput {
-		if slices, err = kbc.FindSlices(table.InPath); err != nil {
-			return err
-		}
-		if totalInputSize, err = slices.Size(); err != nil {
-			return err
-		}
-		if maxSliceSize, err = slices.MaxSliceSize(); err != nil {
+		if slices, totalInputSize, maxSliceSize, unknownInputSize, err = discoverSlices(ctx, table.InPath); err != nil {
 			return err
 		}
-	} else {
+		inputPaths = slices.Paths()
+	} else if !streamInput {
 		totalInputSize = datasize.ByteSize(stat.Size())
 		maxSliceSize = totalInputSize
 	}
 
+	// The expected input size is used instead of the unknown size, if it is specified.
+	// Otherwise, the progress contains only the read bytes and the "slices" mode cannot be used.
+	if unknownInputSize {
+		totalInputSize = table.ExpectedInputSize
+		if totalInputSize == 0 && table.Mode == config.ModeSlices {
+			return kbc.UserErrorf(`the "slices" mode cannot be used, the size of the input "%s" is unknown, please specify the expected input size`, table.InPath)
+		}
+	}
+
+	// STDIN, a named pipe or a device can be read only once
+	if unknownInputSize && table.OutputFormat != config.FormatCSV && table.OutputTypes == config.TypesInferred {
+		return kbc.UserErrorf(`the inferred output types cannot be used with the input "%s" of unknown size, the input would be read twice`, table.InPath)
+	}
+
+	// Columns of a non-CSV input are defined by the schema or the keys
+	inputFormat, err := rowsreader.DetectFormat(table.InputFormat, inputPaths)
+	if err != nil {
+		return err
+	}
+
+	// Check manifest, if the table is sliced
+	if slicedInput && inputFormat == config.FormatCSV && !manifest.Exists() {
+		return kbc.UserErrorf(`the manifest "%s" not found, it is required for the sliced table`, table.InManifestPath)
+	}
+	if slicedInput && inputFormat == config.FormatCSV && !manifest.HasColumns() {
+		return kbc.UserErrorf(`the manifest "%s" has no columns, columns are required for the sliced table`, table.InManifestPath)
+	}
+
 	// Skip table if the maximum slice size is under the threshold.
 	// If the InputSizeThreshold is 0, the feature is disabled, no table is skipped.
-	if maxSliceSize < table.InputSizeThreshold {
-		return skipTable(logger, table, slicedInput, maxSliceSize)
+	// A skipped table is copied without conversion, so the threshold is ignored, if the input or the output format is not CSV.
+	// The threshold is also ignored, if the input size is unknown, the output is STDOUT, or the rows are processed.
+	if maxSliceSize < table.InputSizeThreshold && inputFormat == config.FormatCSV && table.OutputFormat == config.FormatCSV && !unknownInputSize && !streamOutput && !table.processed() {
+		result.InSlices = uint32(max(len(slices), 1))
+		result.InBytes = totalInputSize.Bytes()
+		return skipTable(logger, table, slicedInput, maxSliceSize, result)
+	}
+
+	// Check free disk space before the output is created
+	if !streamOutput {
+		if err := checkDiskSpace(logger, table, estimateOutputSize(table.Config, inputPaths, totalInputSize)); err != nil {
+			return err
+		}
 	}
 
 	// Create target dir
-	if err := utils.Mkdir(table.OutPath); err != nil {
-		return err
+	if !streamOutput {
+		if err := utils.Mkdir(table.OutPath); err != nil {
+			return err
+		}
 	}
 
 	// Create progress logger
 	progressMessage := fmt.Sprintf("Slicing table \"%s\"", table.Name)
 	progressLogger := progress.NewLogger(clock.New(), logger, table.LogInterval, totalInputSize, progressMessage)
+	defer progressLogger.Close()
 	logger.Info(progressMessage + ".") // log initial message
 
+	// Metrics are optional, the methods of a nil *metrics.Table do nothing
+	tableMetrics := table.Metrics.Table(table.Name)
+	progressLogger.TrackMetrics(tableMetrics)
+
 	// Create reader
-	var reader *rowsreader.Reader
-	if slicedInput {
-		reader, err = rowsreader.NewSlicesReader(progressLogger, table.Config, table.InPath, slices, manifest.Delimiter(), manifest.Enclosure())
-		if err != nil {
-			return err
-		}
-	} else {
-		reader, err = rowsreader.NewFileReader(progressLogger, table.Config, table.InPath, manifest.Delimiter(), manifest.Enclosure())
-		if err != nil {
-			return err
-		}
+	reader, err := newReader(ctx, progressLogger, table.Config, table.InPath, slicedInput, slices, manifest)
+	if err != nil {
+		return err
 	}
 
 	// Create writer
@@ -120,37 +255,128 @@ func SliceTable(logger log.Logger, table Table) (err error) {
 	if err != nil {
 		return err
 	}
+	writer.TrackMetrics(tableMetrics)
+	writer.TrackSpans(ctx)
+
+	// Add the written rows and the current part to the progress messages
+	writer.TrackProgress(progressLogger)
 
-	// If manifest without defined columns -> store first row/header to manifest "columns" key
-	addColumnsToManifest := !manifest.HasColumns()
+	// If manifest without defined columns -> store first row/header to manifest "columns" key.
+	// Columns of a non-CSV input are always taken from the input, they are not part of the rows.
+	addColumnsToManifest := !manifest.HasColumns() || inputFormat != config.FormatCSV
+	skipHeader := addColumnsToManifest && inputFormat == config.FormatCSV
 	if addColumnsToManifest {
-		if header, err := reader.Header(); err == nil {
-			manifest.SetColumns(header)
-		} else {
+		_, span := tracing.Start(ctx, "read header")
+		header, err := reader.Header()
+		tracing.EndWithError(span, err)
+		if err != nil {
 			return err
 		}
+		manifest.SetColumns(header)
 	}
 
-	// Read all rows from input table and write to sliced table
-	for reader.Read() {
-		if err := writer.Write(reader.Bytes()); err != nil {
+	// Check the memory plan, if the columns of the profile were not known
+	if table.OutProfilePath != "" && profiledColumns != len(manifest.Columns()) {
+		if err := plan.SetProfiledColumns(len(manifest.Columns())); err != nil {
 			return err
 		}
 	}
 
-	// Close the reader
-	if err = reader.Close(); err != nil {
-		return fmt.Errorf("error when reading CSV \"%s\": %w", table.InPath, err)
+	// Define columns of a typed output format.
+	// The types inferred by the first pass are reused by the InferTypes, so the input is not read three times.
+	var inferredColumns []typeinfer.Column
+	if table.OutputFormat != config.FormatCSV {
+		columns, err := outputColumns(logger, table, slicedInput, slices, manifest, totalInputSize, skipHeader)
+		if err != nil {
+			return err
+		}
+		writer.SetSchema(columns, manifest.Delimiter(), manifest.Enclosure())
+		if table.OutputTypes == config.TypesInferred {
+			inferredColumns = columns
+		}
 	}
 
-	// Close the writer
-	if err = writer.Close(); err != nil {
+	// Track min/max values of the columns in each slice
+	if len(table.ZoneMapColumns) > 0 {
+		columnIndexes, err := pipeline.ColumnIndexes(manifest.Columns(), table.ZoneMapColumns)
+		if err != nil {
+			return kbc.UserErrorf("invalid zone map columns: %w", err)
+		}
+		writer.TrackZoneMap(table.ZoneMapColumns, columnIndexes, manifest.Delimiter(), manifest.Enclosure())
+	}
+
+	// Temporary files of the stages are removed, if the slicing fails
+	var aborters []pipeline.Aborter
+	defer func() {
+		if err != nil {
+			for _, aborter := range aborters {
+				err = errors.Join(err, aborter.Abort())
+			}
+		}
+	}()
+
+	// Create optional stages between the reader and the writer, from the last one
+	var out pipeline.Writer = writer
+	var profiler *profile.Profiler
+	if table.OutProfilePath != "" {
+		profiler = profile.New(out, manifest.Columns(), manifest.Delimiter(), manifest.Enclosure())
+		out = profiler
+	}
+	var inferrer *typeinfer.Inferrer
+	if table.InferTypes && inferredColumns == nil {
+		inferrer = typeinfer.New(out, manifest.Columns(), manifest.Delimiter(), manifest.Enclosure())
+		out = inferrer
+	}
+	if len(table.SortBy) > 0 {
+		keyColumns, err := pipeline.ColumnIndexes(manifest.Columns(), table.SortBy)
+		if err != nil {
+			return kbc.UserErrorf("invalid sort columns: %w", err)
+		}
+		writer.TrackKeyRange(keyColumns, manifest.Delimiter(), manifest.Enclosure())
+		rowsSorter, err := sorter.New(table.Config, out, manifest.Columns(), manifest.Delimiter(), manifest.Enclosure())
+		if err != nil {
+			return err
+		}
+		out = rowsSorter
+		aborters = append(aborters, rowsSorter)
+	}
+	var sample *sampler.Sampler
+	if table.Sample.Enabled() {
+		if sample, err = sampler.New(table.Sample, table.SpillBufferSize, out); err != nil {
+			return err
+		}
+		out = sample
+	}
+	var deduplicator *dedup.Deduplicator
+	if table.Deduplication != config.DeduplicationNone {
+		primaryKey, err := manifest.PrimaryKey()
+		if err != nil {
+			return err
+		}
+		deduplicator, err = dedup.New(table.Config, out, manifest.Columns(), primaryKey, manifest.Delimiter(), manifest.Enclosure(), totalInputSize)
+		if err != nil {
+			return err
+		}
+		out = deduplicator
+		aborters = append(aborters, deduplicator)
+	}
+
+	// Read all rows from input table and write to sliced table
+	if err := readRows(ctx, table, reader, out); err != nil {
+		return err
+	}
+
+	// Close the stages and the writer
+	_, span = tracing.Start(ctx, "close writer")
+	err = out.Close()
+	tracing.EndWithError(span, err)
+	if err != nil {
 		return err
 	}
 
-	// Get output size
+	// Get output size, the compressed size of STDOUT is not known
 	outBytes := writer.AlLBytes()
-	if writer.GzipEnabled() {
+	if (writer.GzipEnabled() || table.OutputFormat != config.FormatCSV) && !streamOutput {
 		if dirSize, err := utils.DirSize(table.OutPath); err == nil {
 			outBytes = dirSize
 		} else {
@@ -158,36 +384,142 @@ func SliceTable(logger log.Logger, table Table) (err error) {
 		}
 	}
 
+	// Store inferred column types to the manifest
+	if inferrer != nil {
+		inferredColumns = inferrer.Columns()
+	}
+	if table.InferTypes {
+		if err := setColumnTypes(manifest, inferredColumns); err != nil {
+			return err
+		}
+	}
+
 	// Write manifest
-	if err := manifest.WriteTo(table.OutManifestPath); err != nil {
+	_, span = tracing.Start(ctx, "write manifest")
+	err = manifest.WriteTo(table.OutManifestPath)
+	tracing.EndWithError(span, err)
+	if err != nil {
 		return err
 	}
 
+	// Write parts metadata
+	if table.OutPartsMetadataPath != "" {
+		if err := writePartsMetadata(table, writer.Parts()); err != nil {
+			return err
+		}
+	}
+
+	// Write profile
+	if profiler != nil {
+		if err := writeProfile(table, profiler.Profile()); err != nil {
+			return err
+		}
+	}
+
+	// Get input size
+	inBytes := totalInputSize
+	if unknownInputSize {
+		inBytes = progressLogger.Read()
+	}
+
 	// Log statistics
 	msg := fmt.Sprintf(
 		"Table \"%s\" sliced: in/out: %d / %d slices, %s / %s bytes, %s rows",
 		table.Name,
 		reader.Slices(), writer.Slices(),
-		utils.RemoveSpaces(totalInputSize.HumanReadable()),
+		utils.RemoveSpaces(inBytes.HumanReadable()),
 		utils.RemoveSpaces(outBytes.HumanReadable()),
 		humanize.Comma(int64(writer.AllRows())),
 	)
+	if deduplicator != nil {
+		msg += fmt.Sprintf(", %s duplicates removed", humanize.Comma(int64(deduplicator.Duplicates())))
+	}
+	if sample != nil {
+		msg += fmt.Sprintf(", sampled from %s rows", humanize.Comma(int64(sample.InRows())))
+	}
 
 	switch {
 	case !manifest.Exists():
-		msg += ", manifest created"
+		result.Manifest = ManifestCreated
 	case manifest.Modified():
-		msg += ", manifest updated"
+		result.Manifest = ManifestUpdated
 	default:
-		msg += ", manifest unaffected"
+		result.Manifest = ManifestUnaffected
+	}
+	msg += ", manifest " + result.Manifest + "."
+
+	// Fill result
+	result.Status = ResultSliced
+	result.InSlices = reader.Slices()
+	result.OutSlices = writer.Slices()
+	result.InBytes = inBytes.Bytes()
+	result.OutBytes = outBytes.Bytes()
+	result.OutBytesUncompressed = writer.AlLBytes().Bytes()
+	result.Rows = writer.AllRows()
+
+	logger.Infow(msg,
+		"inSlices", result.InSlices,
+		"outSlices", result.OutSlices,
+		"inBytes", result.InBytes,
+		"outBytes", result.OutBytes,
+		"rows", result.Rows,
+	)
+	return nil
+}
+
+// discoverSlices finds slices of the sliced input table and their sizes.
+func discoverSlices(ctx context.Context, inPath string) (slices kbc.Slices, totalSize, maxSliceSize datasize.ByteSize, unknownSize bool, err error) {
+	_, span := tracing.Start(ctx, "discover slices")
+	defer func() {
+		span.SetAttributes(attribute.Int("slices", len(slices)))
+		tracing.EndWithError(span, err)
+	}()
+
+	if slices, err = kbc.FindSlices(inPath); err != nil {
+		return nil, 0, 0, false, err
+	}
+	if totalSize, err = slices.Size(); err != nil {
+		return nil, 0, 0, false, err
+	}
+	if maxSliceSize, err = slices.MaxSliceSize(); err != nil {
+		return nil, 0, 0, false, err
+	}
+	if unknownSize, err = slices.HasUnknownSize(); err != nil {
+		return nil, 0, 0, false, err
+	}
+	return slices, totalSize, maxSliceSize, unknownSize, nil
+}
+
+// readRows reads all rows from the reader and writes them to the output, the reader is closed at the end.
+func readRows(ctx context.Context, table Table, reader *rowsreader.Reader, out pipeline.Writer) (err error) {
+	_, span := tracing.Start(ctx, "read and write rows")
+	defer func() { tracing.EndWithError(span, err) }()
+
+	for reader.Read() {
+		if err := out.Write(reader.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	// Close the reader
+	if err := reader.Close(); err != nil {
+		return fmt.Errorf("error when reading CSV \"%s\": %w", table.InPath, err)
 	}
-	msg += "."
 
-	logger.Info(msg)
 	return nil
 }
 
-func skipTable(logger log.Logger, table Table, slicedInput bool, maxSliceSize datasize.ByteSize) error {
+func newReader(ctx context.Context, progressLogger *progress.Logger, cfg config.Config, inPath string, slicedInput bool, slices kbc.Slices, manifest *manifestPkg.Manifest) (*rowsreader.Reader, error) {
+	if inPath == kbc.StdStreamPath {
+		return rowsreader.NewStreamReader(ctx, progressLogger, cfg, os.Stdin, manifest.Delimiter(), manifest.Enclosure())
+	}
+	if slicedInput {
+		return rowsreader.NewSlicesReader(ctx, progressLogger, cfg, inPath, slices, manifest.Delimiter(), manifest.Enclosure())
+	}
+	return rowsreader.NewFileReader(ctx, progressLogger, cfg, inPath, manifest.Delimiter(), manifest.Enclosure())
+}
+
+func skipTable(logger log.Logger, table Table, slicedInput bool, maxSliceSize datasize.ByteSize, result *Result) error {
 	if slicedInput {
 		logger.Infof(`Skipping table "%s": maximum size of slice "%s" is smaller than the threshold "%s".`, table.Name, maxSliceSize, table.InputSizeThreshold)
 	} else {
@@ -196,7 +528,13 @@ func skipTable(logger log.Logger, table Table, slicedInput bool, maxSliceSize da
 
 	// Exit without copying if flag is present
 	if table.InputSizeLowExitCode != 0 {
-		os.Exit(int(table.InputSizeLowExitCode))
+		result.Status = ResultSkipped
+		return kbc.ExitCodeErrorf(int(table.InputSizeLowExitCode), `table "%s" is smaller than the input size threshold`, table.Name)
+	}
+
+	// Check free disk space, the table is copied 1:1
+	if err := checkDiskSpace(logger, table, datasize.ByteSize(result.InBytes)); err != nil {
+		return err
 	}
 
 	// Copy table
@@ -209,10 +547,17 @@ func skipTable(logger log.Logger, table Table, slicedInput bool, maxSliceSize da
 		if err := utils.CopyRecursive(table.InManifestPath, table.OutManifestPath); err != nil {
 			return err
 		}
+		result.Manifest = ManifestCopied
 	} else if err != nil {
 		return err
 	}
 
+	// The table is not read, so the rows are not counted
+	result.Status = ResultCopied
+	result.OutSlices = result.InSlices
+	result.OutBytes = result.InBytes
+	result.OutBytesUncompressed = result.InBytes
+
 	logger.Infof(`Table "%s" has been copied to the output without modification.`, table.Name)
 	return nil
 }
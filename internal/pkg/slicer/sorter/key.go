@@ -0,0 +1,193 @@
+package sorter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strconv"
+)
+
+const (
+	typeNumber = 0x01 // numbers are ordered before strings
+	typeString = 0x02
+	escape     = 0x00
+	escaped    = 0xff // escape + escaped = 0x00 in the value
+	terminator = 0x01 // escape + terminator = end of the value
+
+	signNegative = 0x00
+	signZero     = 0x01
+	signPositive = 0x02
+	// A shorter number is lower, if it is positive, and higher, if it is negative, see AppendValue.
+	positiveTerminator = 0x00
+	negativeTerminator = 0xff
+	// maxExponent limits the exponent notation, a larger exponent is not a number.
+	maxExponent = 1 << 30
+)
+
+// AppendKey appends order-preserving encoding of the key columns to the buffer.
+// Encoded keys can be compared by bytes.Compare:
+//   - Numeric values are compared as numbers and are ordered before other values.
+//   - Other values are compared as bytes.
+//
+// A missing value, for example in an empty row, is treated as an empty string.
+func AppendKey(buf []byte, fields [][]byte, keyColumns []int) []byte {
+	for _, index := range keyColumns {
+		var value []byte
+		if index < len(fields) {
+			value = fields[index]
+		}
+		buf = AppendValue(buf, value)
+	}
+	return buf
+}
+
+// CompareValues compares two values in the same natural order as the encoded keys.
+func CompareValues(a, b []byte) int {
+	return bytes.Compare(AppendValue(nil, a), AppendValue(nil, b))
+}
+
+// AppendValue appends order-preserving encoding of the value to the buffer, see AppendKey.
+//
+// A number is encoded as an exact decimal, so large integers, for example 64-bit IDs, are not rounded:
+// the sign, the exponent and the significant digits, the digits of a negative number are inverted.
+func AppendValue(buf []byte, value []byte) []byte {
+	if d, ok := parseNumber(value); ok {
+		buf = append(buf, typeNumber)
+		switch {
+		case len(d.digits) == 0 && !d.inf:
+			return append(buf, signZero)
+		case d.negative:
+			buf = append(buf, signNegative)
+			buf = binary.BigEndian.AppendUint32(buf, ^d.biasedExponent())
+			for _, digit := range d.digits {
+				buf = append(buf, '0'+'9'-digit)
+			}
+			return append(buf, negativeTerminator)
+		default:
+			buf = append(buf, signPositive)
+			buf = binary.BigEndian.AppendUint32(buf, d.biasedExponent())
+			buf = append(buf, d.digits...)
+			return append(buf, positiveTerminator)
+		}
+	}
+
+	buf = append(buf, typeString)
+	for _, char := range value {
+		if char == escape {
+			buf = append(buf, escape, escaped)
+		} else {
+			buf = append(buf, char)
+		}
+	}
+	return append(buf, escape, terminator)
+}
+
+// decimal is a normalized number 0.digits × 10^exponent, digits have no leading and trailing zeros.
+type decimal struct {
+	negative bool
+	inf      bool
+	digits   []byte
+	exponent int
+}
+
+// biasedExponent maps the exponent to an unsigned number with the same order, the infinity is the maximum.
+func (d decimal) biasedExponent() uint32 {
+	if d.inf {
+		return math.MaxUint32
+	}
+	return uint32(int64(d.exponent) + math.MaxInt32)
+}
+
+func parseNumber(value []byte) (decimal, bool) {
+	// Fast path, number must start with a digit, sign or dot
+	if len(value) == 0 {
+		return decimal{}, false
+	}
+	if first := value[0]; !(first >= '0' && first <= '9') && first != '-' && first != '+' && first != '.' {
+		return decimal{}, false
+	}
+
+	// Decimal notation is parsed exactly
+	if d, ok := parseDecimal(value); ok {
+		return d, true
+	}
+
+	// Other notations, for example hexadecimal or infinity, are parsed as float
+	number, err := strconv.ParseFloat(string(value), 64)
+	if err != nil || math.IsNaN(number) {
+		return decimal{}, false
+	}
+	if math.IsInf(number, 0) {
+		return decimal{negative: number < 0, inf: true}, true
+	}
+	return parseDecimal(strconv.AppendFloat(nil, number, 'e', -1, 64))
+}
+
+// parseDecimal parses the "[sign]digits[.digits][e[sign]digits]" notation.
+func parseDecimal(value []byte) (decimal, bool) {
+	var d decimal
+	i := 0
+	if i < len(value) && (value[i] == '+' || value[i] == '-') {
+		d.negative = value[i] == '-'
+		i++
+	}
+
+	// Significant digits, the exponent is the number of the integer digits
+	var digits int
+	for ; i < len(value) && value[i] >= '0' && value[i] <= '9'; i++ {
+		d.appendDigit(value[i])
+		d.exponent++
+		digits++
+	}
+	if i < len(value) && value[i] == '.' {
+		for i++; i < len(value) && value[i] >= '0' && value[i] <= '9'; i++ {
+			d.appendDigit(value[i])
+			digits++
+		}
+	}
+	if digits == 0 {
+		return decimal{}, false
+	}
+
+	// Exponent, it is limited, so it cannot overflow
+	if i < len(value) && (value[i] == 'e' || value[i] == 'E') {
+		i++
+		negative := false
+		if i < len(value) && (value[i] == '+' || value[i] == '-') {
+			negative = value[i] == '-'
+			i++
+		}
+		start, exponent := i, 0
+		for ; i < len(value) && value[i] >= '0' && value[i] <= '9'; i++ {
+			if exponent = exponent*10 + int(value[i]-'0'); exponent > maxExponent {
+				return decimal{}, false
+			}
+		}
+		if i == start {
+			return decimal{}, false
+		}
+		if negative {
+			exponent = -exponent
+		}
+		d.exponent += exponent
+	}
+	if i != len(value) {
+		return decimal{}, false
+	}
+
+	// Leading zeros were skipped, each of them decreased the exponent, trailing zeros are removed
+	d.digits = bytes.TrimRight(d.digits, "0")
+	if len(d.digits) == 0 {
+		// Normalize zero, including the negative zero
+		return decimal{}, true
+	}
+	return d, true
+}
+
+func (d *decimal) appendDigit(digit byte) {
+	if digit == '0' && len(d.digits) == 0 {
+		d.exponent--
+		return
+	}
+	d.digits = append(d.digits, digit)
+}
@@ -0,0 +1,313 @@
+// Package sorter provides external sort of rows by key columns.
+//
+// Rows are sorted in memory while they fit into the configured SpillBufferSize.
+// Then each sorted run is spilled to a temporary file and the runs are merged on Close.
+// The sort is stable, rows with the same key keep the input order.
+package sorter
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/c2h5oh/datasize"
+
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/columnsparser"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/pipeline"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/spill"
+)
+
+const (
+	// rowOverhead is an estimated memory usage of one row in the buffer, without the key and the row.
+	rowOverhead = 64
+	// maxFanIn limits the number of runs merged at once, each opened run allocates a read buffer.
+	maxFanIn = 64
+)
+
+// Sorter sorts rows by the key columns.
+type Sorter struct {
+	next       pipeline.Writer
+	tempDir    string
+	maxMemory  datasize.ByteSize
+	parser     *columnsparser.Parser
+	keyColumns []int
+
+	rows  []keyedRow
+	size  datasize.ByteSize
+	runs  []*spill.File
+	files []*spill.File // all created runs, including the merged ones, they are removed by the Abort
+	count uint64
+}
+
+type keyedRow struct {
+	key []byte
+	row []byte
+}
+
+// New creates the Sorter stage.
+func New(cfg config.Config, next pipeline.Writer, columns []string, delimiter, enclosure byte) (*Sorter, error) {
+	keyColumns, err := pipeline.ColumnIndexes(columns, cfg.SortBy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sort columns: %w", err)
+	}
+
+	return &Sorter{
+		next:       next,
+		tempDir:    cfg.TempDir,
+		maxMemory:  cfg.SpillBufferSize,
+		parser:     columnsparser.NewParser(delimiter, enclosure),
+		keyColumns: keyColumns,
+	}, nil
+}
+
+func (s *Sorter) Write(row []byte) error {
+	s.count++
+
+	fields, err := s.parser.Fields(row)
+	if err != nil {
+		return fmt.Errorf("cannot parse sort key of the row %d: %w", s.count, err)
+	}
+
+	item := keyedRow{key: AppendKey(nil, fields, s.keyColumns), row: pipeline.CopyRow(row)}
+	s.rows = append(s.rows, item)
+	s.size += datasize.ByteSize(len(item.key) + len(item.row) + rowOverhead)
+
+	if s.size > s.maxMemory {
+		return s.spillRun()
+	}
+	return nil
+}
+
+// Close writes sorted rows to the next stage and closes it.
+// On an error, all remaining runs are removed.
+func (s *Sorter) Close() (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, s.Abort())
+		}
+	}()
+
+	if len(s.runs) == 0 {
+		// All rows fit into the memory
+		s.sortRows()
+		for _, item := range s.rows {
+			if err := s.next.Write(item.row); err != nil {
+				return err
+			}
+		}
+		s.rows = nil
+	} else {
+		// Spill the rest and merge all runs
+		if len(s.rows) > 0 {
+			if err := s.spillRun(); err != nil {
+				return err
+			}
+		}
+		if err := s.mergeRuns(); err != nil {
+			return err
+		}
+		s.files = nil
+	}
+
+	return s.next.Close()
+}
+
+// Abort removes all remaining runs, if the slicing fails.
+func (s *Sorter) Abort() error {
+	var errs []error
+	for _, file := range s.files {
+		if err := file.Remove(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.files = nil
+	return errors.Join(errs...)
+}
+
+// Runs returns the number of the sorted runs spilled to disk.
+func (s *Sorter) Runs() int {
+	return len(s.runs)
+}
+
+func (s *Sorter) sortRows() {
+	sort.SliceStable(s.rows, func(i, j int) bool {
+		return bytes.Compare(s.rows[i].key, s.rows[j].key) < 0
+	})
+}
+
+func (s *Sorter) spillRun() error {
+	s.sortRows()
+
+	run, err := s.createRun()
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, run)
+
+	var record []byte
+	for _, item := range s.rows {
+		record = encodeRecord(record[:0], item.key, item.row)
+		if err := run.Write(record); err != nil {
+			return err
+		}
+	}
+
+	s.rows = nil
+	s.size = 0
+	return nil
+}
+
+func (s *Sorter) mergeRuns() (err error) {
+	runs := s.runs
+	defer func() {
+		for _, run := range runs {
+			if removeErr := run.Remove(); err == nil && removeErr != nil {
+				err = removeErr
+			}
+		}
+	}()
+
+	// Reduce the number of runs, if there are too many of them.
+	// The order of the runs is preserved, so the sort remains stable.
+	for len(runs) > maxFanIn {
+		var merged []*spill.File
+		for start := 0; start < len(runs); start += maxFanIn {
+			group := runs[start:min(start+maxFanIn, len(runs))]
+			target, err := s.createRun()
+			if err != nil {
+				return err
+			}
+			merged = append(merged, target)
+			err = mergeTo(group, func(record []byte) error {
+				return target.Write(record)
+			})
+			for _, run := range group {
+				if removeErr := run.Remove(); err == nil && removeErr != nil {
+					err = removeErr
+				}
+			}
+			if err != nil {
+				runs = append(merged, runs[start+len(group):]...)
+				return err
+			}
+		}
+		runs = merged
+	}
+
+	return mergeTo(runs, func(record []byte) error {
+		_, row, err := decodeRecord(record)
+		if err != nil {
+			return err
+		}
+		return s.next.Write(row)
+	})
+}
+
+func (s *Sorter) createRun() (*spill.File, error) {
+	run, err := spill.Create(s.tempDir)
+	if err != nil {
+		return nil, err
+	}
+	s.files = append(s.files, run)
+	return run, nil
+}
+
+// mergeTo merges sorted runs, each record is passed to the callback, the record is valid only during the call.
+func mergeTo(runs []*spill.File, fn func(record []byte) error) error {
+	h := &runsHeap{}
+	for i, run := range runs {
+		if err := run.Rewind(); err != nil {
+			return err
+		}
+		if err := h.pushNext(run, i); err != nil {
+			return err
+		}
+	}
+
+	for h.Len() > 0 {
+		head := heap.Pop(h).(*runHead)
+		if err := fn(head.record); err != nil {
+			return err
+		}
+		if err := h.pushNext(head.run, head.index); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeRecord(buf []byte, key, row []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(key)))
+	buf = append(buf, key...)
+	return append(buf, row...)
+}
+
+func decodeRecord(record []byte) (key, row []byte, err error) {
+	keyLength, n := binary.Uvarint(record)
+	if n <= 0 || n+int(keyLength) > len(record) {
+		return nil, nil, fmt.Errorf("invalid record in the spill file")
+	}
+	return record[n : n+int(keyLength)], record[n+int(keyLength):], nil
+}
+
+// runHead is the current record of a run.
+type runHead struct {
+	run    *spill.File
+	index  int // index of the run, it makes the merge stable
+	key    []byte
+	record []byte
+}
+
+type runsHeap []*runHead
+
+// pushNext reads the next record from the run, nothing is pushed at the end of the run.
+func (h *runsHeap) pushNext(run *spill.File, index int) error {
+	record, err := run.Next()
+	if errors.Is(err, io.EOF) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	key, _, err := decodeRecord(record)
+	if err != nil {
+		return err
+	}
+
+	heap.Push(h, &runHead{run: run, index: index, key: key, record: record})
+	return nil
+}
+
+func (h runsHeap) Len() int {
+	return len(h)
+}
+
+func (h runsHeap) Less(i, j int) bool {
+	if c := bytes.Compare(h[i].key, h[j].key); c != 0 {
+		return c < 0
+	}
+	return h[i].index < h[j].index
+}
+
+func (h runsHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *runsHeap) Push(x any) {
+	*h = append(*h, x.(*runHead))
+}
+
+func (h *runsHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
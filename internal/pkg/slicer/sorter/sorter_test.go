@@ -0,0 +1,225 @@
+package sorter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+)
+
+type rowsCollector struct {
+	rows   []string
+	closed bool
+}
+
+func (c *rowsCollector) Write(row []byte) error {
+	c.rows = append(c.rows, string(row))
+	return nil
+}
+
+func (c *rowsCollector) Close() error {
+	c.closed = true
+	return nil
+}
+
+type failingWriter struct {
+	rows   int
+	failAt int
+}
+
+func (w *failingWriter) Write([]byte) error {
+	w.rows++
+	if w.rows >= w.failAt {
+		return errors.New("some error")
+	}
+	return nil
+}
+
+func (w *failingWriter) Close() error {
+	return nil
+}
+
+func TestCompareValues(t *testing.T) {
+	t.Parallel()
+
+	ordered := []string{"-10", "-1.5", "0", "2", "10", "1e3", "", "-", "10a", "a", "b", "b\x00", "ba"}
+	for i := 0; i < len(ordered); i++ {
+		for j := 0; j < len(ordered); j++ {
+			expected := 0
+			if i < j {
+				expected = -1
+			} else if i > j {
+				expected = 1
+			}
+			assert.Equal(t, expected, CompareValues([]byte(ordered[i]), []byte(ordered[j])), "%q <=> %q", ordered[i], ordered[j])
+		}
+	}
+
+	// Same number, different formatting
+	assert.Equal(t, 0, CompareValues([]byte("1.0"), []byte("1")))
+	assert.Equal(t, 0, CompareValues([]byte("-0"), []byte("0")))
+	assert.Equal(t, 0, CompareValues([]byte("0012.50"), []byte("1.25e1")))
+	assert.Equal(t, 0, CompareValues([]byte("0x1p4"), []byte("16")))
+}
+
+func TestCompareValues_Exact(t *testing.T) {
+	t.Parallel()
+
+	// Integers above 2^53 cannot be represented by float64, they are compared exactly
+	ordered := []string{
+		"-Inf", "-9007199254740993", "-9007199254740992", "-0.001", "0", "0.000123", "0.1", "0.12", "1",
+		"9007199254740992", "9007199254740992.5", "9007199254740993", "9007199254740994",
+		"18446744073709551615", "18446744073709551616", "1e400", "Inf",
+	}
+	for i := 0; i < len(ordered)-1; i++ {
+		assert.Equal(t, -1, CompareValues([]byte(ordered[i]), []byte(ordered[i+1])), "%q < %q", ordered[i], ordered[i+1])
+		assert.Equal(t, 1, CompareValues([]byte(ordered[i+1]), []byte(ordered[i])), "%q > %q", ordered[i+1], ordered[i])
+	}
+}
+
+func TestAppendKey_MultipleColumns(t *testing.T) {
+	t.Parallel()
+
+	key := func(values ...string) []byte {
+		var fields [][]byte
+		for _, v := range values {
+			fields = append(fields, []byte(v))
+		}
+		return AppendKey(nil, fields, []int{0, 1})
+	}
+
+	// The first column has priority, a shorter value is ordered before a longer one
+	assert.Equal(t, -1, bytes.Compare(key("a", "z"), key("b", "a")))
+	assert.Equal(t, -1, bytes.Compare(key("a", "z"), key("ab", "a")))
+	assert.Equal(t, -1, bytes.Compare(key("a", "1"), key("a", "2")))
+	assert.Equal(t, 0, bytes.Compare(key("a", "1"), key("a", "1.0")))
+
+	// Missing column is an empty value
+	assert.Equal(t, 0, bytes.Compare(key("a"), key("a", "")))
+}
+
+func TestSorter_InMemory(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.SortBy = []string{"id"}
+	cfg.TempDir = t.TempDir()
+
+	out := &rowsCollector{}
+	s, err := New(cfg, out, []string{"value", "id"}, ',', '"')
+	require.NoError(t, err)
+	for _, row := range []string{"a,10\n", "b,9\n", "c,10\n", "d,abc\n", "e,1"} {
+		require.NoError(t, s.Write([]byte(row)))
+	}
+	require.NoError(t, s.Close())
+
+	// Numbers before strings, the sort is stable, the last row gets new line
+	assert.Equal(t, []string{"e,1\n", "b,9\n", "a,10\n", "c,10\n", "d,abc\n"}, out.rows)
+	assert.True(t, out.closed)
+	assert.Equal(t, 0, s.Runs())
+}
+
+func TestSorter_Spilled(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.SortBy = []string{"id"}
+	cfg.SpillBufferSize = 1024 // many runs, more than the maxFanIn
+	cfg.TempDir = t.TempDir()
+
+	out := &rowsCollector{}
+	s, err := New(cfg, out, []string{"id", "seq"}, ',', '"')
+	require.NoError(t, err)
+
+	// Ids contain duplicates, to test the sort stability
+	rnd := rand.New(rand.NewSource(1)) // nolint: gosec
+	var expected []string
+	for i := 0; i < 10000; i++ {
+		row := fmt.Sprintf("%d,%d\n", rnd.Intn(1000), i)
+		expected = append(expected, row)
+		require.NoError(t, s.Write([]byte(row)))
+	}
+	require.NoError(t, s.Close())
+	assert.Greater(t, s.Runs(), maxFanIn)
+
+	sort.SliceStable(expected, func(i, j int) bool {
+		var a, b int
+		_, _ = fmt.Sscanf(expected[i], "%d,", &a)
+		_, _ = fmt.Sscanf(expected[j], "%d,", &b)
+		return a < b
+	})
+	assert.Equal(t, expected, out.rows)
+
+	// Spill files are removed
+	entries, err := os.ReadDir(cfg.TempDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestSorter_Abort(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.SortBy = []string{"id"}
+	cfg.SpillBufferSize = 1024 // force spilling
+	cfg.TempDir = t.TempDir()
+
+	s, err := New(cfg, &rowsCollector{}, []string{"id", "seq"}, ',', '"')
+	require.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, s.Write([]byte(fmt.Sprintf("%d,%d\n", i%10, i))))
+	}
+
+	// The slicing failed, the Close is not called
+	entries, err := os.ReadDir(cfg.TempDir)
+	require.NoError(t, err)
+	assert.NotEmpty(t, entries)
+	require.NoError(t, s.Abort())
+	entries, err = os.ReadDir(cfg.TempDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestSorter_Close_Error(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.SortBy = []string{"id"}
+	cfg.SpillBufferSize = 1024 // many runs, more than the maxFanIn
+	cfg.TempDir = t.TempDir()
+
+	// The next stage fails, in the middle of the merge
+	s, err := New(cfg, &failingWriter{failAt: 100}, []string{"id", "seq"}, ',', '"')
+	require.NoError(t, err)
+	for i := 0; i < 10000; i++ {
+		require.NoError(t, s.Write([]byte(fmt.Sprintf("%d,%d\n", i%1000, i))))
+	}
+	err = s.Close()
+	if assert.Error(t, err) {
+		assert.Equal(t, "some error", err.Error())
+	}
+
+	// Spill files are removed
+	entries, err := os.ReadDir(cfg.TempDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestSorter_UnknownColumn(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.SortBy = []string{"foo"}
+	_, err := New(cfg, &rowsCollector{}, []string{"id"}, ',', '"')
+	if assert.Error(t, err) {
+		assert.Equal(t, `invalid sort columns: column "foo" not found in the table columns`, err.Error())
+	}
+}
@@ -0,0 +1,108 @@
+// Package spill provides temporary files for rows that do not fit into the memory.
+// Each record is prefixed by its length, so any binary content can be stored.
+package spill
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/c2h5oh/datasize"
+)
+
+// BufferSize is the size of the write buffer and of the read buffer of a File.
+const BufferSize = 64 * datasize.KB
+
+// File is a temporary file, records are written first, then the file is rewound and records are read.
+type File struct {
+	file    *os.File
+	writer  *bufio.Writer
+	reader  *bufio.Reader
+	record  []byte
+	size    datasize.ByteSize
+	records uint64
+}
+
+// Create a new temporary file in the dir, the system temp dir is used if the dir is empty.
+func Create(dir string) (*File, error) {
+	file, err := os.CreateTemp(dir, "slicer-*.spill")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create spill file: %w", err)
+	}
+	return &File{file: file, writer: bufio.NewWriterSize(file, int(BufferSize))}, nil
+}
+
+// Write one record to the file.
+func (f *File) Write(record []byte) error {
+	var length [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(length[:], uint64(len(record)))
+	if _, err := f.writer.Write(length[:n]); err != nil {
+		return fmt.Errorf("cannot write to spill file \"%s\": %w", f.file.Name(), err)
+	}
+	if _, err := f.writer.Write(record); err != nil {
+		return fmt.Errorf("cannot write to spill file \"%s\": %w", f.file.Name(), err)
+	}
+	f.size += datasize.ByteSize(n + len(record))
+	f.records++
+	return nil
+}
+
+// Rewind flushes written records and prepares the file for reading from the beginning.
+// No record can be written after the Rewind, the read buffer is allocated by the first Next call.
+func (f *File) Rewind() error {
+	if err := f.writer.Flush(); err != nil {
+		return fmt.Errorf("cannot flush spill file \"%s\": %w", f.file.Name(), err)
+	}
+	if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("cannot rewind spill file \"%s\": %w", f.file.Name(), err)
+	}
+	// The write buffer is released, the file is only read from now on
+	f.writer = nil
+	return nil
+}
+
+// Next returns the next record or io.EOF at the end of the file.
+// The record is valid only until the next call.
+func (f *File) Next() ([]byte, error) {
+	if f.reader == nil {
+		f.reader = bufio.NewReaderSize(f.file, int(BufferSize))
+	}
+	length, err := binary.ReadUvarint(f.reader)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(cap(f.record)) < length {
+		f.record = make([]byte, length)
+	}
+	f.record = f.record[:length]
+	if _, err := io.ReadFull(f.reader, f.record); err != nil {
+		return nil, fmt.Errorf("cannot read spill file \"%s\": %w", f.file.Name(), err)
+	}
+	return f.record, nil
+}
+
+// Size of all written records, including the length prefixes.
+func (f *File) Size() datasize.ByteSize {
+	return f.size
+}
+
+// Records returns the number of written records.
+func (f *File) Records() uint64 {
+	return f.records
+}
+
+// Remove closes and deletes the file, it does nothing, if the file is already removed.
+func (f *File) Remove() error {
+	if f.file == nil {
+		return nil
+	}
+	file := f.file
+	f.file, f.writer, f.reader, f.record = nil, nil, nil, nil
+	closeErr := file.Close()
+	if err := os.Remove(file.Name()); err != nil {
+		return fmt.Errorf("cannot remove spill file: %w", err)
+	}
+	return closeErr
+}
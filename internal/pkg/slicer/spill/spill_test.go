@@ -0,0 +1,47 @@
+package spill
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	f, err := Create(dir)
+	require.NoError(t, err)
+
+	records := []string{"foo", "", "bar\nbaz", string(make([]byte, 100*datasize.KB))}
+	for _, record := range records {
+		require.NoError(t, f.Write([]byte(record)))
+	}
+	assert.Equal(t, uint64(4), f.Records())
+	assert.Equal(t, datasize.ByteSize(3+1+0+1+7+1+102400+3), f.Size())
+
+	// Read records back
+	require.NoError(t, f.Rewind())
+	var actual []string
+	for {
+		record, err := f.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		actual = append(actual, string(record))
+	}
+	assert.Equal(t, records, actual)
+
+	// File is deleted, the second call does nothing
+	require.NoError(t, f.Remove())
+	require.NoError(t, f.Remove())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
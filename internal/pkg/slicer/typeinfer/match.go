@@ -0,0 +1,125 @@
+package typeinfer
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+)
+
+const (
+	dateLayout = "2006-01-02"
+)
+
+// timestampLayouts are tried in order, the fractional seconds are optional in all layouts.
+func timestampLayouts() []string {
+	return []string{
+		"2006-01-02 15:04:05.999999999",
+		"2006-01-02T15:04:05.999999999",
+		"2006-01-02 15:04:05.999999999Z07:00",
+		"2006-01-02T15:04:05.999999999Z07:00",
+	}
+}
+
+// matchingCandidates returns the subset of the current candidates matching the non-empty value.
+func matchingCandidates(value []byte, current candidates) candidates {
+	var out candidates
+	if current&candidateInteger != 0 && isInteger(value) {
+		out |= candidateInteger
+	}
+	if current&candidateNumeric != 0 && isNumeric(value) {
+		out |= candidateNumeric
+	}
+	if current&candidateBoolean != 0 && isBoolean(value) {
+		out |= candidateBoolean
+	}
+	if current&(candidateDate|candidateTimestamp) != 0 && looksLikeDate(value) {
+		str := string(value)
+		if isDate(str) {
+			// A date is also a valid timestamp, so the dates and the timestamps can be mixed in a TIMESTAMP column
+			out |= current & (candidateDate | candidateTimestamp)
+		} else if current&candidateTimestamp != 0 && isTimestamp(str) {
+			out |= candidateTimestamp
+		}
+	}
+	return out
+}
+
+// isInteger accepts a decimal integer which fits into int64.
+// Numbers with leading zeros, for example "007", are codes rather than numbers, so they are rejected.
+func isInteger(value []byte) bool {
+	digits := trimSign(value)
+	if !isDigits(digits) || hasLeadingZero(digits) {
+		return false
+	}
+	_, err := strconv.ParseInt(string(value), 10, 64)
+	return err == nil
+}
+
+// isNumeric accepts a decimal number with an optional fractional part, the exponent notation is rejected.
+func isNumeric(value []byte) bool {
+	digits := trimSign(value)
+	integerPart, fractionalPart, found := bytes.Cut(digits, []byte("."))
+	if !isDigits(integerPart) || hasLeadingZero(integerPart) {
+		return false
+	}
+	return !found || isDigits(fractionalPart)
+}
+
+func isBoolean(value []byte) bool {
+	return bytes.EqualFold(value, []byte("true")) || bytes.EqualFold(value, []byte("false"))
+}
+
+// ParseDate parses a value of the DATE column.
+func ParseDate(value string) (time.Time, error) {
+	return time.Parse(dateLayout, value)
+}
+
+// ParseTimestamp parses a value of the TIMESTAMP column, a date is also accepted.
+// A value without a time zone is in UTC.
+func ParseTimestamp(value string) (t time.Time, err error) {
+	for _, layout := range append(timestampLayouts(), dateLayout) {
+		if t, err = time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return t, err
+}
+
+func isDate(value string) bool {
+	_, err := ParseDate(value)
+	return err == nil
+}
+
+func isTimestamp(value string) bool {
+	_, err := ParseTimestamp(value)
+	return err == nil
+}
+
+// looksLikeDate is a fast check before the time.Parse, the value must start with "YYYY-".
+func looksLikeDate(value []byte) bool {
+	return len(value) >= len(dateLayout) && isDigits(value[:4]) && value[4] == '-'
+}
+
+func trimSign(value []byte) []byte {
+	if len(value) > 0 && (value[0] == '-' || value[0] == '+') {
+		return value[1:]
+	}
+	return value
+}
+
+func hasLeadingZero(digits []byte) bool {
+	return len(digits) > 1 && digits[0] == '0'
+}
+
+// isDigits returns true if the value is not empty and contains only digits.
+func isDigits(value []byte) bool {
+	if len(value) == 0 {
+		return false
+	}
+	for _, char := range value {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,166 @@
+// Package typeinfer provides inference of the column types from the values.
+//
+// Each column starts with all types as candidates.
+// A candidate is removed when a value doesn't match it.
+// The most specific remaining type is the result, STRING matches all values.
+//
+// An empty value is a null, it doesn't affect the type, but the column is nullable.
+// A column with only null values is a nullable STRING.
+//
+// The precision and the scale of a NUMERIC column are the maximum numbers of digits, so all values fit without rounding.
+package typeinfer
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/columnsparser"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/pipeline"
+)
+
+// BaseType of a column, the values match the Keboola base types.
+type BaseType string
+
+const (
+	TypeInteger   BaseType = "INTEGER"
+	TypeNumeric   BaseType = "NUMERIC"
+	TypeBoolean   BaseType = "BOOLEAN"
+	TypeDate      BaseType = "DATE"
+	TypeTimestamp BaseType = "TIMESTAMP"
+	TypeString    BaseType = "STRING"
+)
+
+// candidates is a bit set of the remaining types.
+type candidates uint8
+
+const (
+	candidateInteger candidates = 1 << iota
+	candidateNumeric
+	candidateBoolean
+	candidateDate
+	candidateTimestamp
+	allCandidates = candidateInteger | candidateNumeric | candidateBoolean | candidateDate | candidateTimestamp
+)
+
+// Column is the inferred type of a column.
+type Column struct {
+	Name     string
+	Type     BaseType
+	Nullable bool
+	// Precision is the total number of digits of a NUMERIC column, it is 0 for other types.
+	Precision int
+	// Scale is the number of digits after the decimal point of a NUMERIC column, it is 0 for other types.
+	Scale int
+}
+
+// Inferrer infers the column types from the rows passed to the next stage.
+type Inferrer struct {
+	next    pipeline.Writer
+	parser  *columnsparser.Parser
+	columns []string
+	states  []columnState
+	rows    uint64
+}
+
+type columnState struct {
+	candidates candidates
+	nullable   bool
+	notNull    bool // at least one value is not null
+	intDigits  int  // maximum number of digits before the decimal point of the numeric values
+	scale      int  // maximum number of digits after the decimal point of the numeric values
+}
+
+// New creates the Inferrer stage.
+func New(next pipeline.Writer, columns []string, delimiter, enclosure byte) *Inferrer {
+	states := make([]columnState, len(columns))
+	for i := range states {
+		states[i].candidates = allCandidates
+	}
+	return &Inferrer{
+		next:    next,
+		parser:  columnsparser.NewParser(delimiter, enclosure),
+		columns: columns,
+		states:  states,
+	}
+}
+
+func (t *Inferrer) Write(row []byte) error {
+	t.rows++
+
+	fields, err := t.parser.Fields(row)
+	if err != nil {
+		return fmt.Errorf("cannot parse the row %d to infer types: %w", t.rows, err)
+	}
+
+	for i := range t.states {
+		state := &t.states[i]
+
+		// A missing value, for example in an empty row, is a null
+		var value []byte
+		if i < len(fields) {
+			value = fields[i]
+		}
+		if len(value) == 0 {
+			state.nullable = true
+			continue
+		}
+
+		state.notNull = true
+		if state.candidates != 0 {
+			state.candidates &= matchingCandidates(value, state.candidates)
+			if state.candidates&candidateNumeric != 0 {
+				state.addDigits(value)
+			}
+		}
+	}
+
+	return t.next.Write(row)
+}
+
+// Close closes the next stage.
+func (t *Inferrer) Close() error {
+	return t.next.Close()
+}
+
+// Columns returns the inferred types, the result is complete after Close.
+func (t *Inferrer) Columns() []Column {
+	out := make([]Column, len(t.columns))
+	for i, name := range t.columns {
+		state := t.states[i]
+		out[i] = Column{Name: name, Type: TypeString, Nullable: state.nullable}
+		if state.notNull {
+			out[i].Type = state.candidates.baseType()
+		}
+		if out[i].Type == TypeNumeric {
+			out[i].Precision = state.intDigits + state.scale
+			out[i].Scale = state.scale
+		}
+	}
+	return out
+}
+
+// addDigits updates the maximum numbers of digits by the numeric value.
+func (s *columnState) addDigits(value []byte) {
+	integerPart, fractionalPart, _ := bytes.Cut(trimSign(value), []byte("."))
+	s.intDigits = max(s.intDigits, len(integerPart))
+	s.scale = max(s.scale, len(fractionalPart))
+}
+
+// baseType returns the most specific remaining type.
+// For example, a column with "1" and "2" values matches both INTEGER and NUMERIC, the INTEGER wins.
+func (c candidates) baseType() BaseType {
+	switch {
+	case c&candidateInteger != 0:
+		return TypeInteger
+	case c&candidateNumeric != 0:
+		return TypeNumeric
+	case c&candidateBoolean != 0:
+		return TypeBoolean
+	case c&candidateDate != 0:
+		return TypeDate
+	case c&candidateTimestamp != 0:
+		return TypeTimestamp
+	default:
+		return TypeString
+	}
+}
@@ -0,0 +1,120 @@
+package typeinfer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type rowsCollector struct {
+	rows   []string
+	closed bool
+}
+
+func (c *rowsCollector) Write(row []byte) error {
+	c.rows = append(c.rows, string(row))
+	return nil
+}
+
+func (c *rowsCollector) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestInferrer(t *testing.T) {
+	t.Parallel()
+
+	columns := []string{"int", "numeric", "bool", "date", "timestamp", "mixedDates", "string", "code", "exp", "nullable", "empty"}
+	rows := []string{
+		`"1","1.5","true","2023-01-31","2023-01-31 10:20:30","2023-01-31","a","001","1e5","1",""` + "\n",
+		`"-20","2","FALSE","2024-02-29","2023-01-31T10:20:30.123Z","2023-01-31 10:20:30","1","002","2","",""` + "\n",
+		`"+300","-0.25","True","1999-12-31","2023-01-31T10:20:30+02:00","2023-02-01","2023-13-01","3","3","2",""`, // last row without new line
+	}
+
+	out := &rowsCollector{}
+	inferrer := New(out, columns, ',', '"')
+	for _, row := range rows {
+		require.NoError(t, inferrer.Write([]byte(row)))
+	}
+	require.NoError(t, inferrer.Close())
+
+	// Rows are passed to the next stage without modification
+	assert.Equal(t, rows, out.rows)
+	assert.True(t, out.closed)
+
+	assert.Equal(t, []Column{
+		{Name: "int", Type: TypeInteger},
+		{Name: "numeric", Type: TypeNumeric, Precision: 3, Scale: 2},
+		{Name: "bool", Type: TypeBoolean},
+		{Name: "date", Type: TypeDate},
+		{Name: "timestamp", Type: TypeTimestamp},
+		{Name: "mixedDates", Type: TypeTimestamp},
+		{Name: "string", Type: TypeString},
+		{Name: "code", Type: TypeString},
+		{Name: "exp", Type: TypeString},
+		{Name: "nullable", Type: TypeInteger, Nullable: true},
+		{Name: "empty", Type: TypeString, Nullable: true},
+	}, inferrer.Columns())
+}
+
+func TestInferrer_MissingValues(t *testing.T) {
+	t.Parallel()
+
+	inferrer := New(&rowsCollector{}, []string{"a", "b"}, ',', '"')
+	require.NoError(t, inferrer.Write([]byte("\"1\",\"2\"\n")))
+	require.NoError(t, inferrer.Write([]byte("\"3\"\n")))
+	require.NoError(t, inferrer.Close())
+
+	assert.Equal(t, []Column{
+		{Name: "a", Type: TypeInteger},
+		{Name: "b", Type: TypeInteger, Nullable: true},
+	}, inferrer.Columns())
+}
+
+func TestInferrer_NumericPrecision(t *testing.T) {
+	t.Parallel()
+
+	inferrer := New(&rowsCollector{}, []string{"amount", "big"}, ',', '"')
+	require.NoError(t, inferrer.Write([]byte("\"12345678901234567.89\",\"1\"\n")))
+	require.NoError(t, inferrer.Write([]byte("\"-0.125\",\"\"\n")))
+	require.NoError(t, inferrer.Write([]byte("\"7\",\"9223372036854775808\"\n")))
+	require.NoError(t, inferrer.Close())
+
+	// The integer digits and the scale are the maximums of different values
+	assert.Equal(t, []Column{
+		{Name: "amount", Type: TypeNumeric, Precision: 20, Scale: 3},
+		{Name: "big", Type: TypeNumeric, Nullable: true, Precision: 19},
+	}, inferrer.Columns())
+}
+
+func TestMatchingCandidates(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		value    string
+		expected BaseType
+	}{
+		{value: "0", expected: TypeInteger},
+		{value: "-0", expected: TypeInteger},
+		{value: "9223372036854775807", expected: TypeInteger},
+		{value: "9223372036854775808", expected: TypeNumeric},
+		{value: "0.5", expected: TypeNumeric},
+		{value: "00.5", expected: TypeString},
+		{value: "1.", expected: TypeString},
+		{value: ".5", expected: TypeString},
+		{value: "-", expected: TypeString},
+		{value: "NaN", expected: TypeString},
+		{value: "false", expected: TypeBoolean},
+		{value: "yes", expected: TypeString},
+		{value: "2023-02-29", expected: TypeString},
+		{value: "2023-02-28", expected: TypeDate},
+		{value: "2023-02-28 25:00:00", expected: TypeString},
+		{value: "2023-02-28 23:59:59.999999", expected: TypeTimestamp},
+		{value: "2023-02-28T23:59:59-05:00", expected: TypeTimestamp},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.expected, matchingCandidates([]byte(tc.value), allCandidates).baseType(), tc.value)
+	}
+}
@@ -0,0 +1,74 @@
+package slicer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/benbjohnson/clock"
+	"github.com/c2h5oh/datasize"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/log"
+	manifestPkg "github.com/keboola/processor-split-table/internal/pkg/manifest"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/rowsreader/progress"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/typeinfer"
+)
+
+// discard is the last stage of the types inference pass, nothing is written.
+type discard struct{}
+
+func (discard) Write([]byte) error {
+	return nil
+}
+
+func (discard) Close() error {
+	return nil
+}
+
+// outputColumns returns columns of a typed output format.
+// The inferred types must be known before the first row is written, so the input is read twice.
+// Types are inferred from all input rows, so they are valid also for the deduplicated or sampled rows.
+func outputColumns(logger log.Logger, table Table, slicedInput bool, slices kbc.Slices, manifest *manifestPkg.Manifest, totalInputSize datasize.ByteSize, skipHeader bool) ([]typeinfer.Column, error) {
+	// All columns are strings
+	if table.OutputTypes != config.TypesInferred {
+		columns := make([]typeinfer.Column, len(manifest.Columns()))
+		for i, name := range manifest.Columns() {
+			columns[i] = typeinfer.Column{Name: name, Type: typeinfer.TypeString}
+		}
+		return columns, nil
+	}
+
+	// Create reader
+	progressMessage := fmt.Sprintf("Inferring types of table \"%s\"", table.Name)
+	progressLogger := progress.NewLogger(clock.New(), logger, table.LogInterval, totalInputSize, progressMessage)
+	defer progressLogger.Close()
+	logger.Info(progressMessage + ".") // log initial message
+	reader, err := newReader(context.Background(), progressLogger, table.Config, table.InPath, slicedInput, slices, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip header, it has been already read by the main reader
+	if skipHeader {
+		if _, err := reader.Header(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Infer types
+	inferrer := typeinfer.New(discard{}, manifest.Columns(), manifest.Delimiter(), manifest.Enclosure())
+	for reader.Read() {
+		if err := inferrer.Write(reader.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	if err = reader.Close(); err != nil {
+		return nil, fmt.Errorf("error when reading CSV \"%s\": %w", table.InPath, err)
+	}
+	if err = inferrer.Close(); err != nil {
+		return nil, err
+	}
+
+	return inferrer.Columns(), nil
+}
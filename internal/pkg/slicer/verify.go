@@ -0,0 +1,96 @@
+package slicer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/benbjohnson/clock"
+	"github.com/dustin/go-humanize"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/keboola/processor-split-table/internal/pkg/kbc"
+	"github.com/keboola/processor-split-table/internal/pkg/log"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/columnsparser"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/config"
+	"github.com/keboola/processor-split-table/internal/pkg/slicer/rowsreader/progress"
+	"github.com/keboola/processor-split-table/internal/pkg/utils"
+)
+
+// VerifyTable reads all rows of the input table, without any output.
+// Each row must be a valid CSV row with the same number of columns as the header.
+// The first invalid row is reported as a user error.
+func VerifyTable(logger log.Logger, table InspectedTable) error {
+	// Validate
+	table.setDefaultName()
+	logger = log.With(logger, "table", table.Name)
+	val := validator.New()
+	if err := val.Struct(table); err != nil {
+		return kbc.UserErrorf(`table definition is not valid: %w`, err)
+	}
+
+	// Open input
+	in, err := openInput(table.InPath, table.InManifestPath)
+	if err != nil {
+		return err
+	}
+	manifest := in.manifest
+
+	// Create progress logger
+	progressMessage := fmt.Sprintf("Verifying table \"%s\"", table.Name)
+	progressLogger := progress.NewLogger(clock.New(), logger, table.LogInterval, in.size, progressMessage)
+	defer progressLogger.Close()
+	logger.Info(progressMessage + ".") // log initial message
+
+	// Create reader
+	reader, err := newReader(context.Background(), progressLogger, table.Config, table.InPath, in.sliced, in.slices, manifest)
+	if err != nil {
+		return err
+	}
+
+	// Get columns, a CSV file without the manifest columns starts with the header
+	var columns []string
+	if manifest.HasColumns() && reader.Format() == config.FormatCSV {
+		columns = manifest.Columns()
+	} else if columns, err = reader.Header(); err != nil {
+		_ = reader.Close()
+		return err
+	}
+
+	// Validate all rows
+	var rows uint64
+	parser := columnsparser.NewParser(manifest.Delimiter(), manifest.Enclosure())
+	for reader.Read() {
+		rows++
+		fields, err := parser.Fields(reader.Bytes())
+		if err != nil {
+			_ = reader.Close()
+			return kbc.UserErrorf(`invalid row %d of table "%s": %w`, rows, table.Name, err)
+		}
+
+		// An empty row is one empty value
+		count := len(fields)
+		if count == 0 {
+			count = 1
+		}
+		if count != len(columns) {
+			_ = reader.Close()
+			return kbc.UserErrorf(`invalid row %d of table "%s": expected %d columns, found %d`, rows, table.Name, len(columns), count)
+		}
+	}
+
+	// Close the reader
+	if err = reader.Close(); err != nil {
+		return fmt.Errorf("error when reading CSV \"%s\": %w", table.InPath, err)
+	}
+
+	// Log statistics
+	logger.Infof(
+		"Table \"%s\" verified: %d slices, %s bytes, %s rows, %d columns.",
+		table.Name,
+		reader.Slices(),
+		utils.RemoveSpaces(in.size.HumanReadable()),
+		humanize.Comma(int64(rows)),
+		len(columns),
+	)
+	return nil
+}
@@ -0,0 +1,83 @@
+// Package tracing provides OpenTelemetry spans of the slicing, exported over OTLP.
+// Spans are started from the parent span in the context, so the tracing is disabled, if the context has no span.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdkTrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	instrumentationName = "github.com/keboola/processor-split-table"
+	serviceName         = "slicer"
+)
+
+// Config of the tracing.
+type Config struct {
+	Endpoint string `json:"otlpEndpoint" mapstructure:"otlp-endpoint" validate:"omitempty,url"` // optional, for example "http://localhost:4318"
+}
+
+// Enabled returns true, if the spans are exported.
+func (c Config) Enabled() bool {
+	return c.Endpoint != ""
+}
+
+// NewProvider creates the tracer provider exporting spans to the OTLP HTTP endpoint.
+// The provider must be shut down to flush the spans.
+func NewProvider(cfg Config) (*sdkTrace.TracerProvider, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid OTLP endpoint "%s": %w`, cfg.Endpoint, err)
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint.Host)}
+	if endpoint.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if endpoint.Path != "" && endpoint.Path != "/" {
+		opts = append(opts, otlptracehttp.WithURLPath(endpoint.Path))
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf(`cannot create OTLP exporter: %w`, err)
+	}
+
+	return sdkTrace.NewTracerProvider(
+		sdkTrace.WithBatcher(exporter),
+		sdkTrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	), nil
+}
+
+// StartRoot starts the root span by the provider, a nil provider disables tracing.
+func StartRoot(provider trace.TracerProvider, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if provider == nil {
+		provider = noop.NewTracerProvider()
+	}
+	return provider.Tracer(instrumentationName).Start(context.Background(), name, trace.WithAttributes(attrs...))
+}
+
+// Start starts the child span of the span in the context, by the same provider.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	tracer := trace.SpanFromContext(ctx).TracerProvider().Tracer(instrumentationName)
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndWithError ends the span, the error is recorded, if any.
+func EndWithError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkTrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStartRoot_NilProvider(t *testing.T) {
+	t.Parallel()
+
+	ctx, span := StartRoot(nil, "root")
+	assert.False(t, span.IsRecording())
+
+	// Children are also disabled
+	_, child := Start(ctx, "child")
+	assert.False(t, child.IsRecording())
+	child.End()
+	span.End()
+}
+
+func TestStart(t *testing.T) {
+	t.Parallel()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdkTrace.NewTracerProvider(sdkTrace.WithSpanProcessor(recorder))
+
+	ctx, root := StartRoot(provider, "root", attribute.String("table", "my-table"))
+	_, child := Start(ctx, "child")
+	EndWithError(child, errors.New("some error"))
+	EndWithError(root, nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	// Child span is started by the provider from the context
+	assert.Equal(t, "child", spans[0].Name())
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "some error", spans[0].Status().Description)
+
+	assert.Equal(t, "root", spans[1].Name())
+	assert.Equal(t, []attribute.KeyValue{attribute.String("table", "my-table")}, spans[1].Attributes())
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+}
+
+func TestNewProvider(t *testing.T) {
+	t.Parallel()
+
+	// Fake OTLP collector
+	var requests atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/v1/traces" {
+			requests.Add(1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	provider, err := NewProvider(Config{Endpoint: collector.URL})
+	require.NoError(t, err)
+
+	_, span := StartRoot(provider, "root")
+	span.End()
+
+	// Spans are flushed on shutdown
+	require.NoError(t, provider.Shutdown(context.Background()))
+	assert.Equal(t, int32(1), requests.Load())
+}
@@ -73,6 +73,8 @@ func AssertDirectoryContentsSame(t *testing.T, expectedDir string, dataDir strin
 		"diff",
 		"--exclude=.gitkeep",
 		"--ignore-all-space",
+		// Durations in the result files are not deterministic
+		`--ignore-matching-lines="\(durationSeconds\|throughputBytesPerSecond\)":`,
 		"--recursive",
 		expectedDirAbs,
 		dataDirAbs,
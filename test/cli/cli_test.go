@@ -24,6 +24,7 @@ import (
 
 // TestCLI runs all data-dir tests from the file directory.
 // In "envs" and "args" fi $IN_DIR and $OUT_DIR placeholder.
+// The optional "stdin" file is piped to STDIN of the command.
 func TestCLI(t *testing.T) {
 	t.Parallel()
 
@@ -113,6 +114,14 @@ func RunDataDirTest(t *testing.T, workingDir, testDir string, binary string) {
 	cmd.Args = args
 	cmd.Env = envs
 
+	// Optional "stdin" file is used as STDIN
+	if stdin, err := os.Open(filepath.Join(testDir, "stdin")); err == nil {
+		defer stdin.Close()
+		cmd.Stdin = stdin
+	} else if !errors.Is(err, os.ErrNotExist) {
+		require.NoError(t, err)
+	}
+
 	// Run command
 	exitCode := 0
 	if err := cmd.Run(); err != nil {